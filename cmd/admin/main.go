@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/kaz-as/test-transactions/config"
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/admin"
+	"github.com/kaz-as/test-transactions/internal/app"
+	"github.com/kaz-as/test-transactions/internal/usecases/general"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+const usage = `usage: admin [-operator name] command [flags]
+
+commands:
+  create-user -balance N
+  transfer    -from ID -to ID -value N -reason CODE [-reference REF]
+  adjust      -user ID -delta N -reason CODE [-reference REF]
+  balance     -user ID [-at RFC3339]
+  history     -user ID [-limit N] [-reference REF] [-meta KEY=VALUE]...
+  reconcile
+
+Every command is recorded in the admin audit table. A transfer or an adjustment
+is recorded in the DB transaction of the change; rerun a failed one with the reference it shows.`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func run(args []string) error {
+	global := flag.NewFlagSet("admin", flag.ContinueOnError)
+	global.Usage = func() { fmt.Fprintln(global.Output(), usage) }
+	operator := global.String("operator", os.Getenv("USER"), "name of the operator recorded in the audit")
+
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+	if global.NArg() == 0 {
+		global.Usage()
+		return errors.New("command expected")
+	}
+	if *operator == "" {
+		return errors.New("operator is required: set -operator or USER")
+	}
+
+	cfg, err := config.NewConfig()
+	if err != nil {
+		return fmt.Errorf("config error: %s", err)
+	}
+
+	db, err := app.OpenDB(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	l := logger.NewWriter(os.Stderr, cfg.Level, cfg.Log.Format).With("operator", *operator)
+	// the operators review the transfers themselves, so the ones the policy sends to review proceed
+	uc, err := app.NewUseCase(l, db, cfg, general.Reviewed())
+	if err != nil {
+		return err
+	}
+
+	a := admin.New(uc, *operator, os.Stdout)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return command(ctx, a, global.Arg(0), global.Args()[1:])
+}
+
+func command(ctx context.Context, a *admin.Admin, name string, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	var (
+		balance = fs.Int64("balance", 0, "initial balance, taken from the primary user")
+		from    = fs.String("from", "", "sender id")
+		to      = fs.String("to", "", "receiver id")
+		value   = fs.Int64("value", 0, "transfer value")
+		user    = fs.String("user", "", "user id")
+		delta   = fs.Int64("delta", 0, "adjustment: positive credits the user, negative debits")
+		reason  = fs.String("reason", "", "reason code")
+		at      = fs.String("at", "", "RFC3339 moment of the balance; now if empty")
+		limit   = fs.Int("limit", 50, "number of the latest transactions")
+		ref     = fs.String("reference", "", "external reference: of the transfer, generated if empty; of the history filter")
+		meta    = metaFlag{}
+	)
+	fs.Var(meta, "meta", "only the transactions with the metadata KEY=VALUE; repeatable")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch name {
+	case admin.ActionCreateUser:
+		return a.CreateUser(ctx, domain.Balance(*balance))
+	case admin.ActionTransfer:
+		return a.Transfer(ctx, domain.UserID(*from), domain.UserID(*to), domain.Balance(*value), *reason, *ref)
+	case admin.ActionAdjust:
+		return a.Adjust(ctx, domain.UserID(*user), domain.Balance(*delta), *reason, *ref)
+	case admin.ActionBalance:
+		var atTime *time.Time
+		if *at != "" {
+			t, err := time.Parse(time.RFC3339Nano, *at)
+			if err != nil {
+				return fmt.Errorf("at: %w", err)
+			}
+			atTime = &t
+		}
+		return a.Balance(ctx, domain.UserID(*user), atTime)
+	case admin.ActionHistory:
+		filter := domain.TxFilter{ExternalReference: *ref, Metadata: meta}
+		return a.History(ctx, domain.UserID(*user), filter, *limit)
+	case admin.ActionReconcile:
+		return a.Reconcile(ctx)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", name, usage)
+	}
+}
+
+// metaFlag collects the KEY=VALUE pairs of the repeated flag.
+type metaFlag map[string]string
+
+func (m metaFlag) String() string {
+	return fmt.Sprint(map[string]string(m))
+}
+
+func (m metaFlag) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("%q is not KEY=VALUE", s)
+	}
+
+	m[key] = value
+	return nil
+}
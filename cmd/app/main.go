@@ -1,25 +1,63 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"os"
+	"time"
 
 	"github.com/kaz-as/test-transactions/config"
 	"github.com/kaz-as/test-transactions/internal/app"
+	"github.com/kaz-as/test-transactions/internal/importer"
+	"github.com/kaz-as/test-transactions/internal/loadgen"
 )
 
+const usage = `usage:
+  app                                  run the server
+  app migrate up|down|status|version   manage the DB schema
+  app import [flags] FILE              run the operations of a JSONL file, see app import -h
+  app loadgen [flags]                  measure a running instance, see app loadgen -h`
+
 func main() {
-	if err := run(); err != nil {
+	if err := run(os.Args[1:]); err != nil {
 		log.Fatalln(err)
 	}
 }
 
-func run() error {
+func run(args []string) error {
 	cfg, err := config.NewConfig()
 	if err != nil {
 		return fmt.Errorf("config error: %s", err)
 	}
 
+	if len(args) == 0 {
+		return serve(cfg)
+	}
+
+	switch args[0] {
+	case "migrate":
+		if len(args) != 2 {
+			return fmt.Errorf("migrate: one command expected\n%s", usage)
+		}
+
+		err = app.Migrate(cfg, args[1])
+		if err != nil {
+			return fmt.Errorf("migrate error: %s", err)
+		}
+
+		return nil
+	case "import":
+		return runImport(cfg, args[1:])
+	case "loadgen":
+		return runLoadGen(cfg, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+func serve(cfg *config.Config) error {
 	application, err := app.New(cfg)
 	defer application.Close()
 	if err != nil {
@@ -33,3 +71,78 @@ func run() error {
 
 	return nil
 }
+
+func runImport(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+
+	var (
+		concurrency = fs.Int("concurrency", 1, "number of lines run at once")
+		cont        = fs.Bool("continue", false, "continue after a failed line instead of stopping")
+		results     = fs.String("results", "", "results file, FILE.results.jsonl by default")
+		resume      = fs.Bool("resume", false, "resume an interrupted import from its results")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("import: one file expected\n%s", usage)
+	}
+
+	opts := app.ImportOptions{
+		Options: importer.Options{
+			Concurrency:     *concurrency,
+			ContinueOnError: *cont,
+		},
+		Input:   fs.Arg(0),
+		Results: *results,
+		Resume:  *resume,
+	}
+	if opts.Results == "" {
+		opts.Results = opts.Input + ".results.jsonl"
+	}
+
+	summary, err := app.Import(cfg, opts)
+
+	fmt.Fprintf(os.Stderr, "import: %d ok, %d failed, %d skipped; results in %s\n",
+		summary.OK, summary.Failed, summary.Skipped, opts.Results)
+
+	if errors.Is(err, importer.ErrStopped) {
+		return fmt.Errorf("import error: %s; fix the line and run again with -resume", err)
+	}
+	if err != nil {
+		return fmt.Errorf("import error: %s", err)
+	}
+
+	return nil
+}
+
+func runLoadGen(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+
+	opts := loadgen.Options{}
+	fs.StringVar(&opts.URL, "url", "", "base URL of the API, http://localhost:HTTP_PORT by default")
+	fs.IntVar(&opts.Users, "users", 100, "number of users created")
+	fs.Int64Var(&opts.Balance, "balance", 1000, "initial balance of every user")
+	fs.Float64Var(&opts.RPS, "rps", 100, "target rate of transfers per second")
+	fs.DurationVar(&opts.Duration, "duration", 30*time.Second, "duration of the load")
+	fs.IntVar(&opts.Concurrency, "concurrency", 64, "max requests in flight")
+	fs.Float64Var(&opts.Zipf, "zipf", 1.1, "skew of the users picked for transfers, above 1")
+	fs.Int64Var(&opts.MaxValue, "max-value", 10, "max value of a transfer")
+	fs.Int64Var(&opts.Seed, "seed", time.Now().UnixNano(), "random seed")
+	reconcile := fs.Bool("reconcile", true, "check the balances against the transactions in the DB at the end")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := app.LoadGen(cfg, opts, *reconcile)
+	if printErr := report.Print(os.Stdout); printErr != nil && err == nil {
+		err = printErr
+	}
+	if err != nil {
+		return fmt.Errorf("loadgen error: %s", err)
+	}
+
+	return nil
+}
@@ -9,29 +9,144 @@ import (
 
 type (
 	Config struct {
-		HTTP `yaml:"http"`
-		Log  `yaml:"logger"`
-		DB   DB `yaml:"db"`
+		HTTP      `yaml:"http"`
+		Log       `yaml:"logger"`
+		Admin     Admin     `yaml:"admin"`
+		GRPC      GRPC      `yaml:"grpc"`
+		DB        DB        `yaml:"db"`
+		Snapshots Snapshots `yaml:"snapshots"`
+		Batching  Batching  `yaml:"batching"`
+		Queue     Queue     `yaml:"queue"`
+		Tracing   Tracing   `yaml:"tracing"`
+		Policy    Policy    `yaml:"policy"`
 	}
 
 	HTTP struct {
 		Port string `env-required:"true" yaml:"port" env:"HTTP_PORT"`
+		// ReadyTimeout limits the DB checks of the readiness probe.
+		ReadyTimeout time.Duration `env-default:"1s" yaml:"ready_timeout" env:"HTTP_READY_TIMEOUT"`
 	}
 
+	// Admin is a listener for the service endpoints that are not part of the API, e.g. metrics.
+	Admin struct {
+		Port string `env-default:"8091" yaml:"port" env:"ADMIN_PORT"`
+	}
+
+	// GRPC API listener.
+	GRPC struct {
+		Port string `env-default:"9090" yaml:"port" env:"GRPC_PORT"`
+	}
+
+	// Log format is json or console.
 	Log struct {
-		Level string `env-required:"true" yaml:"log_level" env:"LOG_LEVEL"`
+		Level  string `env-required:"true" yaml:"log_level" env:"LOG_LEVEL"`
+		Format string `env-default:"json" yaml:"log_format" env:"LOG_FORMAT"`
 	}
 
+	// DB Driver is postgres or sqlite; SQLite keeps the DB in the file at Path and ignores the connection settings,
+	// so they are required by the postgres driver only.
 	DB struct {
-		Host    string        `env-required:"true" yaml:"host" env:"DB_HOST"`
-		Port    string        `env-required:"true" yaml:"port" env:"DB_PORT"`
-		User    string        `env-required:"true" yaml:"user" env:"DB_USER"`
-		Pass    string        `env-required:"true" yaml:"pass" env:"DB_PASS"`
-		Name    string        `env-required:"true" yaml:"name" env:"DB_NAME"`
+		Driver  string        `env-default:"postgres" yaml:"driver" env:"DB_DRIVER"`
+		Path    string        `env-default:"tx.db" yaml:"path" env:"DB_PATH"`
+		Host    string        `yaml:"host" env:"DB_HOST"`
+		Port    string        `yaml:"port" env:"DB_PORT"`
+		User    string        `yaml:"user" env:"DB_USER"`
+		Pass    string        `yaml:"pass" env:"DB_PASS"`
+		Name    string        `yaml:"name" env:"DB_NAME"`
 		Timeout time.Duration `env-default:"500ms" yaml:"timeout" env:"DB_TIMEOUT"`
+		// AutoMigrate applies the pending migrations at startup. Instances starting at once wait for each other.
+		AutoMigrate bool `env-default:"false" yaml:"auto_migrate" env:"DB_AUTO_MIGRATE"`
+
+		// Retry of the transactions aborted by a serialization failure or a deadlock, within Timeout.
+		// RetryAttempts includes the first attempt, so 1 disables retries.
+		RetryAttempts  int           `env-default:"3" yaml:"retry_attempts" env:"DB_RETRY_ATTEMPTS"`
+		RetryBaseDelay time.Duration `env-default:"10ms" yaml:"retry_base_delay" env:"DB_RETRY_BASE_DELAY"`
+		RetryMaxDelay  time.Duration `env-default:"100ms" yaml:"retry_max_delay" env:"DB_RETRY_MAX_DELAY"`
+
+		// PrimaryShards is the number of rows the balance of the primary user is spread over, so creating users
+		// is not serialized on one of them.
+		PrimaryShards int `env-default:"16" yaml:"primary_shards" env:"DB_PRIMARY_SHARDS"`
+
+		// Timestamps of the transactions are taken from the clock of the app or, on Postgres only, of the DB:
+		// as of the start of the DB transaction or of the statement.
+		Timestamps string `env-default:"app" yaml:"timestamps" env:"DB_TIMESTAMPS"`
+
+		// Isolation levels of the DB transactions by operation, e.g. createTx: serializable; the levels are
+		// read_committed, repeatable_read and serializable. The operations not listed keep their own levels.
+		Isolation map[string]string `yaml:"isolation" env:"DB_ISOLATION"`
+		// LockTimeout and StatementTimeout limit the waits within a DB transaction, 0 disables them: a transfer
+		// waiting for a row lock longer is answered with 409, a statement running longer with 503.
+		// SQLite waits for the write lock up to LockTimeout, or Timeout if it is 0.
+		LockTimeout      time.Duration `env-default:"0" yaml:"lock_timeout" env:"DB_LOCK_TIMEOUT"`
+		StatementTimeout time.Duration `env-default:"0" yaml:"statement_timeout" env:"DB_STATEMENT_TIMEOUT"`
+
+		// Pool of the Postgres connections. The connections idle longer than MaxConnIdleTime are closed down
+		// to MinConns, every one is replaced after MaxConnLifetime. StatementCache is the number of the statements
+		// every connection keeps prepared, 0 disables the cache, e.g. behind PgBouncer in the transaction mode.
+		MaxConns        int           `env-default:"10" yaml:"max_conns" env:"DB_MAX_CONNS"`
+		MinConns        int           `env-default:"0" yaml:"min_conns" env:"DB_MIN_CONNS"`
+		MaxConnIdleTime time.Duration `env-default:"3m" yaml:"max_conn_idle_time" env:"DB_MAX_CONN_IDLE_TIME"`
+		MaxConnLifetime time.Duration `env-default:"1h" yaml:"max_conn_lifetime" env:"DB_MAX_CONN_LIFETIME"`
+		StatementCache  int           `env-default:"512" yaml:"statement_cache" env:"DB_STATEMENT_CACHE"`
+	}
+
+	// Tracing exporter is one of: none, stdout, otlp (gRPC).
+	Tracing struct {
+		Exporter     string  `env-default:"none" yaml:"exporter" env:"TRACING_EXPORTER"`
+		OTLPEndpoint string  `env-default:"localhost:4317" yaml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT"`
+		OTLPInsecure bool    `env-default:"true" yaml:"otlp_insecure" env:"TRACING_OTLP_INSECURE"`
+		SampleRatio  float64 `env-default:"1" yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+	}
+
+	// Policy lists the rules of the transfers in the order they are checked; the built-in checks if empty.
+	Policy struct {
+		Rules []PolicyRule `yaml:"rules"`
+	}
+
+	// PolicyRule is a rule by Name with its settings: Decision (deny or review) of the rules that can send
+	// a transfer to review, Limit of max_value and Users of blocked_users.
+	PolicyRule struct {
+		Name     string   `yaml:"name"`
+		Decision string   `yaml:"decision"`
+		Limit    int64    `yaml:"limit"`
+		Users    []string `yaml:"users"`
+	}
+
+	// Batching of the transfers between the users: the ones arriving within Window (0 disables it) of the first
+	// one are applied in one DB transaction, up to MaxSize of them.
+	Batching struct {
+		Window  time.Duration `env-default:"0" yaml:"window" env:"BATCHING_WINDOW"`
+		MaxSize int           `env-default:"100" yaml:"max_size" env:"BATCHING_MAX_SIZE"`
+	}
+
+	// Queue of the transfers of every account in the app instance: up to Depth of them wait for the one
+	// in progress, the next ones are answered with 429 at once. Disabled, they all wait for the row locks in the DB.
+	// It is off by default, as it answers a burst on an account with 429 where it used to wait.
+	Queue struct {
+		Enabled bool `env-default:"false" yaml:"enabled" env:"QUEUE_ENABLED"`
+		Depth   int  `env-default:"32" yaml:"depth" env:"QUEUE_DEPTH"`
+	}
+
+	// Snapshots of balances are taken every Interval (0 disables it) as of Lag ago.
+	Snapshots struct {
+		Interval time.Duration `env-default:"5m" yaml:"interval" env:"SNAPSHOTS_INTERVAL"`
+		Lag      time.Duration `env-default:"1m" yaml:"lag" env:"SNAPSHOTS_LAG"`
 	}
 )
 
+// DB drivers.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Timestamps sources.
+const (
+	TimestampsApp         = "app"
+	TimestampsTransaction = "transaction"
+	TimestampsStatement   = "statement"
+)
+
 // NewConfig returns app config.
 func NewConfig() (*Config, error) {
 	cfg := &Config{}
@@ -46,5 +161,41 @@ func NewConfig() (*Config, error) {
 		return nil, err
 	}
 
+	switch cfg.DB.Driver {
+	case DriverPostgres:
+		if err = cfg.DB.requirePostgres(); err != nil {
+			return nil, err
+		}
+	case DriverSQLite:
+	default:
+		return nil, fmt.Errorf("config error: unknown db driver %q", cfg.DB.Driver)
+	}
+
+	switch cfg.DB.Timestamps {
+	case TimestampsApp:
+	case TimestampsTransaction, TimestampsStatement:
+		if cfg.DB.Driver != DriverPostgres {
+			return nil, fmt.Errorf("config error: db timestamps %q need the postgres driver", cfg.DB.Timestamps)
+		}
+	default:
+		return nil, fmt.Errorf("config error: unknown db timestamps %q", cfg.DB.Timestamps)
+	}
+
+	if cfg.DB.MaxConns < 1 || cfg.DB.MinConns < 0 || cfg.DB.MinConns > cfg.DB.MaxConns {
+		return nil, fmt.Errorf("config error: db pool of %d to %d conns", cfg.DB.MinConns, cfg.DB.MaxConns)
+	}
+
 	return cfg, nil
 }
+
+// requirePostgres checks the connection settings are set, the way env-required would for the postgres driver.
+func (c DB) requirePostgres() error {
+	for _, f := range []struct{ name, value string }{
+		{"DB_HOST", c.Host}, {"DB_PORT", c.Port}, {"DB_USER", c.User}, {"DB_PASS", c.Pass}, {"DB_NAME", c.Name},
+	} {
+		if f.value == "" {
+			return fmt.Errorf("config error: %s is required by the postgres driver", f.name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequirePostgres(t *testing.T) {
+	db := DB{Host: "tx-pg", Port: "5432", User: "root", Pass: "root", Name: "tx"}
+	assert.NoError(t, db.requirePostgres())
+
+	db.Pass = ""
+	assert.EqualError(t, db.requirePostgres(), "config error: DB_PASS is required by the postgres driver")
+}
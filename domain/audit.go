@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// AuditRecord is an action made by an operator with the admin tool.
+type AuditRecord struct {
+	ID       int64
+	At       time.Time
+	Operator string
+	Action   string
+	Reason   string
+	Params   map[string]interface{}
+	// Error is empty if the action succeeded.
+	Error string
+}
+
+type AuditRepository interface {
+	Store(ctx context.Context, tx *sql.Tx, record *AuditRecord) error
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// BalanceEvent is a debit or credit of the user made by a transaction. Seq is the number of the transaction
+// among the ones of the user, see Tx.FromSeq.
+type BalanceEvent struct {
+	TxID      string    `json:"tx_id"`
+	UserID    UserID    `json:"user_id"`
+	Seq       int64     `json:"seq"`
+	Delta     Balance   `json:"delta"`
+	Balance   Balance   `json:"balance"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// EventCursor is a position in the events of a user. They are ordered by the sequence number, which is assigned
+// under the lock of the user, so in the order the transactions are committed; the timestamps may be out of it.
+type EventCursor struct {
+	Seq int64
+}
+
+// Cursor returns the position of the event.
+func (e BalanceEvent) Cursor() EventCursor {
+	return EventCursor{Seq: e.Seq}
+}
+
+// Before reports whether c is before other.
+func (c EventCursor) Before(other EventCursor) bool {
+	return c.Seq < other.Seq
+}
+
+// Next reports whether the event comes right after c, with no events missed in between.
+func (c EventCursor) Next(event BalanceEvent) bool {
+	return event.Seq == c.Seq+1
+}
+
+type EventsRepository interface {
+	// Notify publishes the events to all the app instances once tx is committed.
+	Notify(ctx context.Context, tx *sql.Tx, events ...BalanceEvent) error
+	// Cursor returns the position of the transaction in the events of the user.
+	Cursor(ctx context.Context, tx *sql.Tx, userID UserID, txID string) (EventCursor, error)
+	// ListAfter returns up to limit events of the user after the cursor, the oldest first.
+	ListAfter(ctx context.Context, tx *sql.Tx, userID UserID, after EventCursor, limit int) ([]BalanceEvent, error)
+}
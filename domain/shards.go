@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+)
+
+// PrimaryShard is a part of the balance of the primary user: the shards are locked independently,
+// so creating users is not serialized on a single row.
+type PrimaryShard struct {
+	ID      int
+	Balance Balance
+}
+
+type PrimaryShardsRepository interface {
+	// GetForUpdate locks the shard; sql.ErrNoRows if there is no such shard.
+	GetForUpdate(ctx context.Context, tx *sql.Tx, id int) (*PrimaryShard, error)
+	// ListForUpdate locks all the shards in the order of their ids. The calls are serialized until their
+	// DB transactions end, so the shards created by one are seen and locked by the next.
+	ListForUpdate(ctx context.Context, tx *sql.Tx) ([]PrimaryShard, error)
+	// Save updates the shard, creating it if needed.
+	Save(ctx context.Context, tx *sql.Tx, shard *PrimaryShard) error
+	// Total is the balance of the primary user.
+	Total(ctx context.Context, tx *sql.Tx) (Balance, error)
+}
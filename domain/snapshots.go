@@ -0,0 +1,14 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type SnapshotsRepository interface {
+	// Take stores balances as of upTo for every user that has transactions after the previous snapshot.
+	Take(ctx context.Context, tx *sql.Tx, upTo time.Time) (taken int64, err error)
+	// BalanceAt counts the balance as of the moment from the nearest snapshot and the transactions after it.
+	BalanceAt(ctx context.Context, tx *sql.Tx, userID UserID, at time.Time) (Balance, error)
+}
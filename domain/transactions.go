@@ -3,17 +3,80 @@ package domain
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 )
 
+// ErrDuplicateReference is returned by TxRepository when the sender already has a transaction
+// with the external reference.
+var ErrDuplicateReference = errors.New("duplicate external reference")
+
 type Tx struct {
-	ID        string
-	From      UserID
-	To        UserID
-	Value     Balance
-	Timestamp time.Time
+	ID          string
+	From        UserID
+	To          UserID
+	Value       Balance
+	Timestamp   time.Time
+	Description string
+	// ExternalReference is unique among the transactions of the sender, so a repeated request is rejected.
+	// Empty if not set.
+	ExternalReference string
+	// Metadata is free-form data of the client; it is stored as a JSON object.
+	Metadata map[string]interface{}
+	// FromSeq and ToSeq number the transactions of every user from 1 with no gaps, and FromBalance and ToBalance
+	// are the balances of the users after the transaction; the balance of the primary user is the sum of its shards.
+	FromSeq     int64
+	FromBalance Balance
+	ToSeq       int64
+	ToBalance   Balance
+	// Expect is the state of the sender the client relies on; it is checked, not stored.
+	Expect Precondition
+}
+
+// Posting returns the number of the transaction among the ones of the user and the balance of the user after it.
+func (t Tx) Posting(userID UserID) (seq int64, balance Balance) {
+	if userID == t.From {
+		return t.FromSeq, t.FromBalance
+	}
+
+	return t.ToSeq, t.ToBalance
+}
+
+// Precondition of a conditional transfer, checked once the sender is locked; nil fields are not checked.
+type Precondition struct {
+	Version *int64
+	Balance *Balance
+}
+
+// Quote is what a transfer would result in now. The ledger charges no fees yet, so Fee is 0.
+type Quote struct {
+	NewBalanceFrom Balance
+	NewBalanceTo   Balance
+	Fee            Balance
+	// Violations are the deny and review decisions of all the rules, in their order, if the transfer
+	// would be stopped; the balances are not projected then.
+	Violations []Violation
+}
+
+// Violation is the decision of a transfer policy rule: deny or review, with its reason code.
+type Violation struct {
+	Decision string
+	Rule     string
+	Reason   string
+	Message  string
+}
+
+// TxFilter narrows a history down; the zero value matches every transaction.
+type TxFilter struct {
+	ExternalReference string
+	// Metadata matches the transactions having all the keys with the string values.
+	Metadata map[string]string
 }
 
 type TxRepository interface {
 	Store(ctx context.Context, tx *sql.Tx, transaction *Tx) error
+	// GetByReference returns the transaction of the sender with the external reference; sql.ErrNoRows if none.
+	GetByReference(ctx context.Context, tx *sql.Tx, from UserID, reference string) (*Tx, error)
+	// ListByUser returns the latest transactions of the user, sent or received, the newest first.
+	ListByUser(ctx context.Context, tx *sql.Tx, userID UserID, filter TxFilter, limit int) ([]Tx, error)
 }
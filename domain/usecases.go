@@ -1,8 +1,19 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type UseCase interface {
 	CreateUser(ctx context.Context, user *User) error
 	CreateTx(ctx context.Context, tx *Tx) (newBalanceFrom Balance, newBalanceTo Balance, err error)
+	// QuoteTx runs the transfer like CreateTx, but never commits it.
+	QuoteTx(ctx context.Context, tx *Tx) (Quote, error)
+	// GetUser returns the current balance of the user with its version.
+	GetUser(ctx context.Context, userID UserID) (*User, error)
+	// GetBalance returns the current balance if at is nil, else the balance as of at.
+	GetBalance(ctx context.Context, userID UserID, at *time.Time) (Balance, error)
+	// History returns up to limit latest transactions of the user matching the filter, the newest first.
+	History(ctx context.Context, userID UserID, filter TxFilter, limit int) ([]Tx, error)
 }
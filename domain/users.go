@@ -8,6 +8,11 @@ import (
 type User struct {
 	ID      UserID
 	Balance Balance
+	// Version is incremented by every update of the balance. It is 0 for the primary user:
+	// its balance is kept in the shards, so it is not versioned.
+	Version int64
+	// Seq is the number of the last transaction of the user, see Tx.FromSeq. It is 0 for the primary user.
+	Seq int64
 }
 
 type (
@@ -15,8 +20,20 @@ type (
 	Balance int64
 )
 
+// Discrepancy is a user whose balance is not equal to the sum of its transactions.
+type Discrepancy struct {
+	UserID   UserID
+	Balance  Balance
+	Expected Balance
+}
+
 type UsersRepository interface {
 	Store(ctx context.Context, tx *sql.Tx, user *User) error
+	Get(ctx context.Context, tx *sql.Tx, userID UserID) (*User, error)
 	GetForUpdate(ctx context.Context, tx *sql.Tx, userID UserID) (*User, error)
 	Update(ctx context.Context, tx *sql.Tx, user *User) error
+	// NextSeq numbers a transaction of the user that is not updated otherwise, and locks the user until tx ends.
+	NextSeq(ctx context.Context, tx *sql.Tx, userID UserID) (int64, error)
+	// Discrepancies compares every balance with the initial one and the transactions of the user.
+	Discrepancies(ctx context.Context, tx *sql.Tx) ([]Discrepancy, error)
 }
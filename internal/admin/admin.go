@@ -0,0 +1,332 @@
+// Package admin implements the ledger operations of the admin tool. Every action is recorded in the audit table.
+package admin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/usecases/general"
+)
+
+// Actions recorded in the audit table.
+const (
+	ActionCreateUser = "create-user"
+	ActionTransfer   = "transfer"
+	ActionAdjust     = "adjust"
+	ActionBalance    = "balance"
+	ActionHistory    = "history"
+	ActionReconcile  = "reconcile"
+)
+
+// ReasonCodes are the only accepted reasons of manual transfers and adjustments.
+var ReasonCodes = []string{
+	"correction",
+	"refund",
+	"chargeback",
+	"goodwill",
+	"fraud",
+	"test",
+}
+
+var (
+	ErrReasonRequired = errors.New("reason code is required")
+	ErrUnknownReason  = errors.New("unknown reason code")
+	ErrZeroAdjustment = errors.New("zero adjustment")
+	ErrUnreconciled   = errors.New("balances do not match transactions")
+)
+
+// UseCase is the part of general.UseCase the admin tool needs.
+type UseCase interface {
+	CreateUserAudited(ctx context.Context, user *domain.User, record func() *domain.AuditRecord) error
+	CreateTxAudited(ctx context.Context, tx *domain.Tx, record func() *domain.AuditRecord) (
+		newBalanceFrom domain.Balance,
+		newBalanceTo domain.Balance,
+		err error,
+	)
+	GetBalance(ctx context.Context, userID domain.UserID, at *time.Time) (domain.Balance, error)
+	History(ctx context.Context, userID domain.UserID, filter domain.TxFilter, limit int) ([]domain.Tx, error)
+	Reconcile(ctx context.Context) ([]domain.Discrepancy, error)
+	RecordAudit(ctx context.Context, record *domain.AuditRecord) error
+}
+
+var _ UseCase = (*general.UseCase)(nil)
+
+type Admin struct {
+	uc       UseCase
+	operator string
+	out      io.Writer
+}
+
+// New returns the admin acting on behalf of the operator. Results are written to out.
+func New(uc UseCase, operator string, out io.Writer) *Admin {
+	return &Admin{
+		uc:       uc,
+		operator: operator,
+		out:      out,
+	}
+}
+
+func (a *Admin) CreateUser(ctx context.Context, balance domain.Balance) error {
+	user := domain.User{Balance: balance}
+	params := func() map[string]interface{} {
+		return map[string]interface{}{
+			"user_id": user.ID,
+			"balance": balance,
+		}
+	}
+
+	err := a.uc.CreateUserAudited(ctx, &user, func() *domain.AuditRecord {
+		return a.record(ActionCreateUser, "", params(), nil)
+	})
+	if err != nil {
+		return a.audit(ctx, ActionCreateUser, "", params(), err)
+	}
+
+	_, _ = fmt.Fprintf(a.out, "user %s created with balance %d\n", user.ID, balance)
+	return nil
+}
+
+// Transfer moves value between two users. The reference dedupes the retries of the transfer:
+// one is generated if it is empty, and shown to retry with.
+func (a *Admin) Transfer(
+	ctx context.Context,
+	from, to domain.UserID,
+	value domain.Balance,
+	reason, reference string,
+) error {
+	if err := checkReason(reason); err != nil {
+		return err
+	}
+
+	reference, err := orNewReference(reference)
+	if err != nil {
+		return err
+	}
+
+	tx := domain.Tx{From: from, To: to, Value: value, ExternalReference: reference}
+	params := func() map[string]interface{} {
+		return map[string]interface{}{
+			"tx_id":     tx.ID,
+			"from":      from,
+			"to":        to,
+			"value":     value,
+			"reference": reference,
+		}
+	}
+
+	newBalanceFrom, newBalanceTo, err := a.apply(ctx, ActionTransfer, reason, &tx, params)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(a.out, "tx %s (reference %s): %s balance %d, %s balance %d\n",
+		tx.ID, reference, from, newBalanceFrom, to, newBalanceTo)
+	return nil
+}
+
+// Adjust credits the user by delta, or debits if delta is negative. The counterparty is the primary user,
+// so the adjustment is an ordinary transaction and the ledger stays balanced. The reference is as of Transfer.
+func (a *Admin) Adjust(
+	ctx context.Context,
+	userID domain.UserID,
+	delta domain.Balance,
+	reason, reference string,
+) error {
+	if err := checkReason(reason); err != nil {
+		return err
+	}
+	if delta == 0 {
+		return ErrZeroAdjustment
+	}
+
+	reference, err := orNewReference(reference)
+	if err != nil {
+		return err
+	}
+
+	tx := domain.Tx{From: general.PrimaryUserID, To: userID, Value: delta, ExternalReference: reference}
+	if delta < 0 {
+		tx = domain.Tx{From: userID, To: general.PrimaryUserID, Value: -delta, ExternalReference: reference}
+	}
+	params := func() map[string]interface{} {
+		return map[string]interface{}{
+			"tx_id":     tx.ID,
+			"user_id":   userID,
+			"delta":     delta,
+			"reference": reference,
+		}
+	}
+
+	newBalanceFrom, newBalanceTo, err := a.apply(ctx, ActionAdjust, reason, &tx, params)
+	if err != nil {
+		return err
+	}
+
+	newBalance := newBalanceTo
+	if delta < 0 {
+		newBalance = newBalanceFrom
+	}
+
+	_, _ = fmt.Fprintf(a.out, "tx %s (reference %s): %s balance %d\n", tx.ID, reference, userID, newBalance)
+	return nil
+}
+
+// apply makes the transfer along with its audit record. A failed one is recorded on its own,
+// and its error has the reference to retry with.
+func (a *Admin) apply(
+	ctx context.Context,
+	action, reason string,
+	tx *domain.Tx,
+	params func() map[string]interface{},
+) (domain.Balance, domain.Balance, error) {
+	newBalanceFrom, newBalanceTo, err := a.uc.CreateTxAudited(ctx, tx, func() *domain.AuditRecord {
+		return a.record(action, reason, params(), nil)
+	})
+	if err != nil {
+		err = fmt.Errorf("reference %s: %w", tx.ExternalReference, err)
+		return 0, 0, a.audit(ctx, action, reason, params(), err)
+	}
+
+	return newBalanceFrom, newBalanceTo, nil
+}
+
+// Balance shows the current balance if at is nil, else the balance as of at.
+func (a *Admin) Balance(ctx context.Context, userID domain.UserID, at *time.Time) error {
+	params := map[string]interface{}{"user_id": userID}
+	if at != nil {
+		params["at"] = at.Format(time.RFC3339Nano)
+	}
+
+	balance, err := a.uc.GetBalance(ctx, userID, at)
+	err = a.audit(ctx, ActionBalance, "", params, err)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(a.out, "%s balance %d\n", userID, balance)
+	return nil
+}
+
+func (a *Admin) History(ctx context.Context, userID domain.UserID, filter domain.TxFilter, limit int) error {
+	txs, err := a.uc.History(ctx, userID, filter, limit)
+	params := map[string]interface{}{
+		"user_id": userID,
+		"limit":   limit,
+	}
+	if filter.ExternalReference != "" {
+		params["external_reference"] = filter.ExternalReference
+	}
+	if len(filter.Metadata) > 0 {
+		params["metadata"] = filter.Metadata
+	}
+	err = a.audit(ctx, ActionHistory, "", params, err)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Seq\tTimestamp\tID\tFrom\tTo\tValue\tBalance\tReference")
+	for _, tx := range txs {
+		value := tx.Value
+		if tx.From == userID {
+			value = -value
+		}
+		seq, balance := tx.Posting(userID)
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%+d\t%d\t%s\n", seq,
+			tx.Timestamp.UTC().Format(time.RFC3339Nano), tx.ID, tx.From, tx.To, value, balance, tx.ExternalReference)
+	}
+
+	return w.Flush()
+}
+
+// Reconcile shows the users whose balances do not match their transactions and returns ErrUnreconciled if any.
+func (a *Admin) Reconcile(ctx context.Context) error {
+	discrepancies, err := a.uc.Reconcile(ctx)
+	err = a.audit(ctx, ActionReconcile, "", map[string]interface{}{
+		"discrepancies": len(discrepancies),
+	}, err)
+	if err != nil {
+		return err
+	}
+
+	if len(discrepancies) == 0 {
+		_, _ = fmt.Fprintln(a.out, "all balances match transactions")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "User\tBalance\tExpected\tDiff")
+	for _, d := range discrepancies {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%+d\n", d.UserID, d.Balance, d.Expected, d.Balance-d.Expected)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%d users: %w", len(discrepancies), ErrUnreconciled)
+}
+
+// audit records the action with its result and returns the action error, if any.
+func (a *Admin) audit(
+	ctx context.Context,
+	action, reason string,
+	params map[string]interface{},
+	actionErr error,
+) error {
+	err := a.uc.RecordAudit(ctx, a.record(action, reason, params, actionErr))
+	if err != nil {
+		return errors.Join(actionErr, fmt.Errorf("record audit: %w", err))
+	}
+
+	return actionErr
+}
+
+func (a *Admin) record(action, reason string, params map[string]interface{}, actionErr error) *domain.AuditRecord {
+	record := &domain.AuditRecord{
+		Operator: a.operator,
+		Action:   action,
+		Reason:   reason,
+		Params:   params,
+	}
+	if actionErr != nil {
+		record.Error = actionErr.Error()
+	}
+
+	return record
+}
+
+// orNewReference returns the reference, or a new one if it is empty.
+func orNewReference(reference string) (string, error) {
+	if reference != "" {
+		return reference, nil
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate reference: %w", err)
+	}
+
+	return "admin-" + hex.EncodeToString(b), nil
+}
+
+func checkReason(reason string) error {
+	if reason == "" {
+		return fmt.Errorf("%w: one of %s", ErrReasonRequired, strings.Join(ReasonCodes, ", "))
+	}
+
+	for _, code := range ReasonCodes {
+		if reason == code {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%q: %w: one of %s", reason, ErrUnknownReason, strings.Join(ReasonCodes, ", "))
+}
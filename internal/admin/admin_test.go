@@ -0,0 +1,128 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/usecases/general"
+)
+
+type fakeUseCase struct {
+	UseCase
+
+	txs      []domain.Tx
+	txErr    error
+	audit    []domain.AuditRecord
+	auditErr error
+}
+
+func (f *fakeUseCase) CreateTxAudited(
+	_ context.Context,
+	tx *domain.Tx,
+	record func() *domain.AuditRecord,
+) (domain.Balance, domain.Balance, error) {
+	f.txs = append(f.txs, *tx)
+	if f.txErr != nil {
+		return 0, 0, f.txErr
+	}
+	tx.ID = "tx1"
+	// the audit is in the transaction: the transfer is rolled back if the audit fails
+	if f.auditErr != nil {
+		return 0, 0, f.auditErr
+	}
+	f.audit = append(f.audit, *record())
+	return 10, 20, nil
+}
+
+func (f *fakeUseCase) GetBalance(context.Context, domain.UserID, *time.Time) (domain.Balance, error) {
+	return 42, nil
+}
+
+func (f *fakeUseCase) RecordAudit(_ context.Context, record *domain.AuditRecord) error {
+	f.audit = append(f.audit, *record)
+	return f.auditErr
+}
+
+func TestTransferReason(t *testing.T) {
+	uc := &fakeUseCase{}
+	a := New(uc, "ops", &bytes.Buffer{})
+
+	err := a.Transfer(context.Background(), "a", "b", 1, "", "")
+	assert.ErrorIs(t, err, ErrReasonRequired)
+
+	err = a.Transfer(context.Background(), "a", "b", 1, "because", "")
+	assert.ErrorIs(t, err, ErrUnknownReason)
+
+	assert.Empty(t, uc.txs, "no transfer without a valid reason")
+
+	err = a.Transfer(context.Background(), "a", "b", 1, "refund", "ref1")
+	require.NoError(t, err)
+	require.Len(t, uc.audit, 1)
+	assert.Equal(t, domain.AuditRecord{
+		Operator: "ops",
+		Action:   ActionTransfer,
+		Reason:   "refund",
+		Params: map[string]interface{}{
+			"tx_id":     "tx1",
+			"from":      domain.UserID("a"),
+			"to":        domain.UserID("b"),
+			"value":     domain.Balance(1),
+			"reference": "ref1",
+		},
+	}, uc.audit[0])
+}
+
+func TestAdjust(t *testing.T) {
+	uc := &fakeUseCase{}
+	out := &bytes.Buffer{}
+	a := New(uc, "ops", out)
+
+	require.NoError(t, a.Adjust(context.Background(), "u", 5, "correction", "ref1"))
+	require.NoError(t, a.Adjust(context.Background(), "u", -3, "correction", "ref2"))
+	assert.ErrorIs(t, a.Adjust(context.Background(), "u", 0, "correction", ""), ErrZeroAdjustment)
+
+	assert.Equal(t, []domain.Tx{
+		{From: general.PrimaryUserID, To: "u", Value: 5, ExternalReference: "ref1"},
+		{From: "u", To: general.PrimaryUserID, Value: 3, ExternalReference: "ref2"},
+	}, uc.txs)
+	assert.Equal(t, "tx tx1 (reference ref1): u balance 20\ntx tx1 (reference ref2): u balance 10\n", out.String())
+}
+
+func TestGeneratedReference(t *testing.T) {
+	uc := &fakeUseCase{}
+	a := New(uc, "ops", &bytes.Buffer{})
+
+	require.NoError(t, a.Transfer(context.Background(), "a", "b", 1, "refund", ""))
+	require.NoError(t, a.Transfer(context.Background(), "a", "b", 1, "refund", ""))
+
+	require.Len(t, uc.txs, 2)
+	assert.Regexp(t, "^admin-[0-9a-f]{32}$", uc.txs[0].ExternalReference)
+	assert.NotEqual(t, uc.txs[0].ExternalReference, uc.txs[1].ExternalReference)
+	assert.Equal(t, uc.txs[0].ExternalReference, uc.audit[0].Params["reference"])
+}
+
+func TestAuditFailures(t *testing.T) {
+	errTx := errors.New("tx failed")
+	uc := &fakeUseCase{txErr: errTx}
+	a := New(uc, "ops", &bytes.Buffer{})
+
+	err := a.Transfer(context.Background(), "a", "b", 1, "refund", "ref1")
+	assert.ErrorIs(t, err, errTx)
+	assert.ErrorContains(t, err, "ref1", "the error must have the reference to retry with")
+	require.Len(t, uc.audit, 1, "failed actions must be recorded too")
+	assert.Equal(t, "reference ref1: tx failed", uc.audit[0].Error)
+
+	errAudit := errors.New("audit failed")
+	uc = &fakeUseCase{auditErr: errAudit}
+	a = New(uc, "ops", &bytes.Buffer{})
+
+	err = a.Balance(context.Background(), "a", nil)
+	assert.ErrorIs(t, err, errAudit, "an action must fail if it cannot be recorded")
+}
@@ -0,0 +1,69 @@
+// Package apierr maps the use case errors to the HTTP and gRPC status codes, so both APIs answer alike.
+package apierr
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/kaz-as/test-transactions/internal/usecases/general"
+	"github.com/kaz-as/test-transactions/internal/usecases/queued"
+)
+
+type class struct {
+	err  error
+	http int
+	grpc codes.Code
+}
+
+var classes = []class{
+	{general.ErrUserNotFound, http.StatusNotFound, codes.NotFound},
+	{general.ErrSame, http.StatusBadRequest, codes.InvalidArgument},
+	{general.ErrNegativeTx, http.StatusBadRequest, codes.InvalidArgument},
+	{general.ErrInvalidDetails, http.StatusBadRequest, codes.InvalidArgument},
+	{general.ErrDuplicateReference, http.StatusConflict, codes.AlreadyExists},
+	{general.ErrDenied, http.StatusForbidden, codes.PermissionDenied},
+	{general.ErrReviewRequired, http.StatusForbidden, codes.PermissionDenied},
+	{general.ErrPreconditionFailed, http.StatusPreconditionFailed, codes.FailedPrecondition},
+	{general.ErrInsufficientBalance, http.StatusUnprocessableEntity, codes.FailedPrecondition},
+	{general.ErrTooMuch, http.StatusUnprocessableEntity, codes.FailedPrecondition},
+	// the account is busy with other transfers, the client can try again
+	{general.ErrLockTimeout, http.StatusConflict, codes.Aborted},
+	{general.ErrStatementTimeout, http.StatusServiceUnavailable, codes.Unavailable},
+	{queued.ErrQueueFull, http.StatusTooManyRequests, codes.ResourceExhausted},
+}
+
+// HTTPStatus returns 500 for an error the client cannot fix.
+func HTTPStatus(err error) int {
+	if c, ok := classify(err); ok {
+		return c.http
+	}
+
+	return http.StatusInternalServerError
+}
+
+// GRPCCode returns codes.Internal for an error the client cannot fix.
+func GRPCCode(err error) codes.Code {
+	if c, ok := classify(err); ok {
+		return c.grpc
+	}
+
+	return codes.Internal
+}
+
+// Public reports whether the error message can be shown to the client.
+func Public(err error) bool {
+	_, ok := classify(err)
+	return ok
+}
+
+func classify(err error) (class, bool) {
+	for _, c := range classes {
+		if errors.Is(err, c.err) {
+			return c, true
+		}
+	}
+
+	return class{}, false
+}
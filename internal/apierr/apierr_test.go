@@ -0,0 +1,48 @@
+package apierr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/usecases/general"
+	"github.com/kaz-as/test-transactions/internal/usecases/queued"
+)
+
+func TestMapping(t *testing.T) {
+	tests := []struct {
+		err  error
+		http int
+		grpc codes.Code
+	}{
+		{general.ErrUserNotFound, http.StatusNotFound, codes.NotFound},
+		{general.ErrSame, http.StatusBadRequest, codes.InvalidArgument},
+		{general.ErrNegativeTx, http.StatusBadRequest, codes.InvalidArgument},
+		{general.ErrInvalidDetails, http.StatusBadRequest, codes.InvalidArgument},
+		{general.ErrDuplicateReference, http.StatusConflict, codes.AlreadyExists},
+		// a reference taken by a concurrent transaction, as the repository finds it
+		{domain.ErrDuplicateReference, http.StatusConflict, codes.AlreadyExists},
+		{general.ErrDenied, http.StatusForbidden, codes.PermissionDenied},
+		{general.ErrReviewRequired, http.StatusForbidden, codes.PermissionDenied},
+		{general.ErrPreconditionFailed, http.StatusPreconditionFailed, codes.FailedPrecondition},
+		{general.ErrInsufficientBalance, http.StatusUnprocessableEntity, codes.FailedPrecondition},
+		{general.ErrTooMuch, http.StatusUnprocessableEntity, codes.FailedPrecondition},
+		{general.ErrLockTimeout, http.StatusConflict, codes.Aborted},
+		{general.ErrStatementTimeout, http.StatusServiceUnavailable, codes.Unavailable},
+		{queued.ErrQueueFull, http.StatusTooManyRequests, codes.ResourceExhausted},
+		{errors.New("db is down"), http.StatusInternalServerError, codes.Internal},
+	}
+
+	for _, tt := range tests {
+		err := fmt.Errorf("check failed: %w", tt.err)
+
+		assert.Equal(t, tt.http, HTTPStatus(err), tt.err.Error())
+		assert.Equal(t, tt.grpc, GRPCCode(err), tt.err.Error())
+		assert.Equal(t, tt.http != http.StatusInternalServerError, Public(err), tt.err.Error())
+	}
+}
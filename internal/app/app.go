@@ -1,107 +1,274 @@
 package app
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 
 	"github.com/kaz-as/test-transactions/config"
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/events"
+	"github.com/kaz-as/test-transactions/internal/grpcapi"
 	"github.com/kaz-as/test-transactions/internal/handlers"
+	"github.com/kaz-as/test-transactions/internal/health"
+	"github.com/kaz-as/test-transactions/internal/metrics"
 	"github.com/kaz-as/test-transactions/internal/middlewares"
-	transactions "github.com/kaz-as/test-transactions/internal/transactions/repository/postgres"
+	"github.com/kaz-as/test-transactions/internal/migrate"
+	"github.com/kaz-as/test-transactions/internal/snapshots"
+	"github.com/kaz-as/test-transactions/internal/usecases/batching"
 	"github.com/kaz-as/test-transactions/internal/usecases/general"
-	users "github.com/kaz-as/test-transactions/internal/users/repository/postgres"
+	"github.com/kaz-as/test-transactions/internal/usecases/instrumented"
+	"github.com/kaz-as/test-transactions/internal/usecases/queued"
+	"github.com/kaz-as/test-transactions/pkg/grpcserver"
 	"github.com/kaz-as/test-transactions/pkg/httpserver"
 	"github.com/kaz-as/test-transactions/pkg/logger"
+	"github.com/kaz-as/test-transactions/pkg/tracing"
 )
 
 type App struct {
-	log  logger.Interface
-	srv  *httpserver.Server
-	conn *sql.DB
+	log        logger.Interface
+	srv        *httpserver.Server
+	admin      *httpserver.Server
+	grpc       *grpcserver.Server
+	conn       *sql.DB
+	health     *health.Checker
+	grpcHealth *grpchealth.Server
+	events     *events.Broker
+	snapshots  *snapshots.Job
+	batching   *batching.UseCase
+
+	stopTracing func(context.Context) error
 }
 
 func New(cfg *config.Config) (app App, _ error) {
-	var l logger.Interface = logger.New(cfg.Level)
+	var l logger.Interface = logger.New(cfg.Level, cfg.Log.Format)
 
 	app.log = l
 
-	dsn := fmt.Sprintf("postgresql://%s:%s@%s:%s/%s", cfg.DB.User, cfg.DB.Pass, cfg.DB.Host, cfg.DB.Port, cfg.DB.Name)
-	db, err := sql.Open("pgx", dsn)
+	stopTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		ServiceName:  "tx",
+		Exporter:     cfg.Tracing.Exporter,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		OTLPInsecure: cfg.Tracing.OTLPInsecure,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return app, fmt.Errorf("tracing setup: %s", err)
+	}
+
+	app.stopTracing = stopTracing
+
+	db, err := OpenDB(cfg.DB)
 	if err != nil {
-		return app, fmt.Errorf("db open: %s", err)
+		return app, err
 	}
 
 	app.conn = db
 
-	db.SetConnMaxIdleTime(time.Minute * 3)
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(10)
+	if cfg.DB.AutoMigrate {
+		err = migrate.Run(context.Background(), db, cfg.DB.Driver, migrateLog{l}, migrate.Up)
+		if err != nil {
+			return app, fmt.Errorf("auto-migrate: %s", err)
+		}
+	}
+
+	m := metrics.New(db)
 
-	err = db.Ping()
+	usecase, err := NewUseCase(l, db, cfg, general.Observer(m))
 	if err != nil {
-		return app, fmt.Errorf("db ping: %s", err)
+		return app, err
+	}
+
+	if cfg.Snapshots.Interval > 0 {
+		app.snapshots = snapshots.NewJob(l, usecase, cfg.Snapshots.Interval, cfg.Snapshots.Lag)
+	}
+
+	mwLocal := middlewares.Chain([]middlewares.Middleware{
+		middlewares.SpanName(),
+		middlewares.SwaggerRoute(),
+	})
+
+	// the requests queue for their accounts before they take a DB connection
+	var txUseCase domain.UseCase = usecase
+	if cfg.Queue.Enabled {
+		txUseCase = queued.NewUseCase(txUseCase, cfg.Queue.Depth)
 	}
 
-	usersRepo := users.NewRepo(l)
-	transactionsRepo := transactions.NewRepo(l)
+	// the batched transfers do not queue: a batch already applies the transfers of an account one at a time,
+	// while a queue would let a single transfer of the account into every window
+	if cfg.Batching.Window > 0 {
+		app.batching = batching.NewUseCase(l, txUseCase, usecase, cfg.Batching.Window, cfg.Batching.MaxSize)
+		txUseCase = app.batching
+	}
 
-	usecase := general.NewUseCase(l, db, usersRepo, transactionsRepo, cfg.DB.Timeout)
+	uc := instrumented.NewUseCase(txUseCase, m)
 
-	h, err := handlers.New(l, db, usecase)
+	h, err := handlers.New(l, db, uc, mwLocal)
 	if err != nil {
 		return app, fmt.Errorf("creating main handler: %s", err)
 	}
 
 	mwGlobal := middlewares.Chain([]middlewares.Middleware{
+		middlewares.RequestID(),
+		middlewares.Tracing(),
+		middlewares.Metrics(m),
 		middlewares.Logger(l),
 		middlewares.Recoverer(l),
 	})
 
-	app.srv = httpserver.New(
-		mwGlobal(h),
+	app.health = health.New(l, db, cfg.HTTP.ReadyTimeout, migrate.ExpectedVersion(cfg.DB.Driver))
+
+	// probes are out of the API, so they are neither logged nor traced
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", app.health.Liveness())
+	mux.Handle("/readyz", app.health.Readiness())
+	mux.Handle("/", mwGlobal(h))
+
+	srvOpts := []httpserver.Option{
 		httpserver.Port(cfg.Port),
 		httpserver.Logger(l),
+	}
+
+	// the balance events are delivered by Postgres notifications, SQLite has none
+	if cfg.DB.Driver == config.DriverPostgres {
+		app.events = events.NewBroker(l, DSN(cfg.DB))
+
+		stream := middlewares.Route(handlers.EventsRoute)(handlers.Events(l, usecase, app.events))
+		mux.Handle(handlers.EventsPattern, mwGlobal(stream))
+
+		// the event streams never end by themselves
+		srvOpts = append(srvOpts, httpserver.OnShutdown(app.events.Stop))
+	}
+
+	app.srv = httpserver.New(mux, srvOpts...)
+
+	adminMux := http.NewServeMux()
+	adminMux.Handle("/metrics", m.Handler())
+
+	app.admin = httpserver.New(
+		adminMux,
+		httpserver.Port(cfg.Admin.Port),
+		httpserver.Logger(l),
+	)
+
+	grpcSrv := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(grpcapi.Interceptors(l)...),
+	)
+	grpcapi.Register(grpcSrv, l, uc)
+
+	app.grpcHealth = grpchealth.NewServer()
+	healthpb.RegisterHealthServer(grpcSrv, app.grpcHealth)
+	reflection.Register(grpcSrv)
+
+	app.grpc = grpcserver.New(
+		grpcSrv,
+		grpcserver.Port(cfg.GRPC.Port),
+		grpcserver.Logger(l),
 	)
 
 	return app, nil
 }
 
-// Close must be called at app stop or exit
+// Close must be called at app stop or exit.
+// The servers are shut down first, so the requests in progress can still use the DB.
 func (app App) Close() {
+	if app.srv != nil {
+		err := app.srv.Shutdown()
+		if err != nil {
+			app.log.Error("srv.Shutdown", "error", err)
+		}
+	}
+
+	if app.grpc != nil {
+		app.grpc.Shutdown()
+	}
+
+	if app.admin != nil {
+		err := app.admin.Shutdown()
+		if err != nil {
+			app.log.Error("admin.Shutdown", "error", err)
+		}
+	}
+
+	if app.snapshots != nil {
+		app.snapshots.Stop()
+	}
+
+	if app.batching != nil {
+		app.batching.Stop()
+	}
+
+	if app.events != nil {
+		app.events.Stop()
+	}
+
 	if app.conn != nil {
 		err := app.conn.Close()
 		if err != nil {
-			app.log.Error("db connection cannot be closed: %s", err)
+			app.log.Error("db connection cannot be closed", "error", err)
 		}
 	}
 
-	if app.srv != nil {
-		err := app.srv.Shutdown()
+	if app.stopTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		err := app.stopTracing(ctx)
 		if err != nil {
-			app.log.Error("srv.Shutdown: %s", err)
+			app.log.Error("tracing shutdown", "error", err)
 		}
 	}
 }
 
 // Run returns only application error that cause shutdown, else nil
 func (app App) Run() (ret error) {
+	// the transfers are waited for by the batches from the first request
+	if app.batching != nil {
+		app.batching.Start()
+	}
+
 	app.srv.Start()
+	app.admin.Start()
+	app.grpc.Start()
+
+	if app.events != nil {
+		app.events.Start()
+	}
+
+	if app.snapshots != nil {
+		app.snapshots.Start()
+	}
 
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
 
 	select {
 	case s := <-interrupt:
-		app.log.Info("app - Run - signal: " + s.String())
+		app.log.Info("app - Run - signal", "signal", s.String())
+		// both readiness probes fail while the servers finish the calls in progress
+		app.health.ShutDown()
+		app.grpcHealth.Shutdown()
 	case err := <-app.srv.Notify():
 		ret = fmt.Errorf("srv.Notify: %w", err)
-		app.log.Error(ret)
+		app.log.Error("app - Run", "error", ret)
+	case err := <-app.grpc.Notify():
+		ret = fmt.Errorf("grpc.Notify: %w", err)
+		app.log.Error("app - Run", "error", ret)
+	case err := <-app.admin.Notify():
+		ret = fmt.Errorf("admin.Notify: %w", err)
+		app.log.Error("app - Run", "error", ret)
 	}
 
 	return
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/kaz-as/test-transactions/config"
+	"github.com/kaz-as/test-transactions/internal/importer"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+type ImportOptions struct {
+	importer.Options
+
+	Input string
+	// Results is the output file; its checkpoint journal is next to it with the .checkpoint suffix.
+	Results string
+	// Resume continues an import that has its results already, else they must not exist.
+	Resume bool
+}
+
+// Import runs the operations of a JSONL file against the DB from the config.
+func Import(cfg *config.Config, opts ImportOptions) (importer.Summary, error) {
+	in, err := os.Open(opts.Input)
+	if err != nil {
+		return importer.Summary{}, fmt.Errorf("open input: %w", err)
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	checkpointPath := opts.Results + ".checkpoint"
+
+	state := importer.NewState()
+	if opts.Resume {
+		state, err = loadImportState(opts.Results, checkpointPath)
+		if err != nil {
+			return importer.Summary{}, err
+		}
+	} else if _, err = os.Stat(opts.Results); err == nil {
+		return importer.Summary{}, fmt.Errorf("results %s exist: resume the import or remove them", opts.Results)
+	}
+
+	flags := os.O_CREATE | os.O_RDWR | os.O_APPEND
+	if !opts.Resume {
+		flags |= os.O_TRUNC
+	}
+
+	results, err := os.OpenFile(opts.Results, flags, 0o644)
+	if err != nil {
+		return importer.Summary{}, fmt.Errorf("open results: %w", err)
+	}
+	defer func() {
+		_ = results.Close()
+	}()
+
+	checkpoint, err := os.OpenFile(checkpointPath, flags, 0o644)
+	if err != nil {
+		return importer.Summary{}, fmt.Errorf("open checkpoint: %w", err)
+	}
+	defer func() {
+		_ = checkpoint.Close()
+	}()
+
+	// a crash can leave a torn last line, which must not be glued to the next one
+	for _, f := range []*os.File{results, checkpoint} {
+		if err = endLine(f); err != nil {
+			return importer.Summary{}, err
+		}
+	}
+
+	db, err := OpenDB(cfg.DB)
+	if err != nil {
+		return importer.Summary{}, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	l := logger.NewWriter(os.Stderr, cfg.Level, cfg.Log.Format)
+	uc, err := NewUseCase(l, db, cfg)
+	if err != nil {
+		return importer.Summary{}, err
+	}
+
+	im := importer.New(l, uc, opts.Options)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return im.Run(ctx, in, results, checkpoint, state)
+}
+
+func loadImportState(resultsPath, checkpointPath string) (*importer.State, error) {
+	open := func(path string) (io.ReadCloser, error) {
+		f, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return io.NopCloser(strings.NewReader("")), nil
+		}
+		return f, err
+	}
+
+	results, err := open(resultsPath)
+	if err != nil {
+		return nil, fmt.Errorf("open results: %w", err)
+	}
+	defer func() {
+		_ = results.Close()
+	}()
+
+	checkpoint, err := open(checkpointPath)
+	if err != nil {
+		return nil, fmt.Errorf("open checkpoint: %w", err)
+	}
+	defer func() {
+		_ = checkpoint.Close()
+	}()
+
+	return importer.LoadState(results, checkpoint)
+}
+
+// endLine appends a line break to a file that does not end with one.
+func endLine(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", f.Name(), err)
+	}
+	if info.Size() == 0 {
+		return nil
+	}
+
+	last := make([]byte, 1)
+	if _, err = f.ReadAt(last, info.Size()-1); err == nil && last[0] == '\n' {
+		return nil
+	}
+
+	if _, err = f.Write([]byte{'\n'}); err != nil {
+		return fmt.Errorf("write %s: %w", f.Name(), err)
+	}
+
+	return nil
+}
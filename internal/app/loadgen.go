@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/kaz-as/test-transactions/config"
+	"github.com/kaz-as/test-transactions/internal/loadgen"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+// LoadGen runs the load against the API of a local instance. Unless the URL is set, the port is taken from the config.
+// With reconcile, the balances are checked against the transactions in the DB from the config at the end.
+func LoadGen(cfg *config.Config, opts loadgen.Options, reconcile bool) (loadgen.Report, error) {
+	if opts.URL == "" {
+		opts.URL = "http://localhost:" + cfg.HTTP.Port
+	}
+
+	l := logger.NewWriter(os.Stderr, cfg.Level, cfg.Log.Format)
+
+	var reconciler loadgen.Reconciler
+	if reconcile {
+		db, err := OpenDB(cfg.DB)
+		if err != nil {
+			return loadgen.Report{}, err
+		}
+		defer func() {
+			_ = db.Close()
+		}()
+
+		reconciler, err = NewUseCase(l, db, cfg)
+		if err != nil {
+			return loadgen.Report{}, err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return loadgen.New(l, reconciler, opts).Run(ctx)
+}
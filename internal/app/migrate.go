@@ -0,0 +1,38 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kaz-as/test-transactions/config"
+	"github.com/kaz-as/test-transactions/internal/migrate"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+// Migrate runs a migrate command (up, down, status or version) against the DB from the config.
+func Migrate(cfg *config.Config, command string) error {
+	db, err := OpenDB(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	return migrate.Run(context.Background(), db, cfg.DB.Driver, os.Stdout, command)
+}
+
+// migrateLog writes every line of the migrate output as a log message.
+type migrateLog struct {
+	log logger.Interface
+}
+
+func (w migrateLog) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimSpace(string(p)), "\n") {
+		w.log.Info(fmt.Sprintf("migrate: %s", line))
+	}
+
+	return len(p), nil
+}
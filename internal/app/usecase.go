@@ -0,0 +1,215 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/kaz-as/test-transactions/config"
+	auditrepo "github.com/kaz-as/test-transactions/internal/audit/repository/postgres"
+	auditsqlite "github.com/kaz-as/test-transactions/internal/audit/repository/sqlite"
+	eventsrepo "github.com/kaz-as/test-transactions/internal/events/repository/postgres"
+	eventssqlite "github.com/kaz-as/test-transactions/internal/events/repository/sqlite"
+	"github.com/kaz-as/test-transactions/internal/policy"
+	shards "github.com/kaz-as/test-transactions/internal/shards/repository/postgres"
+	shardssqlite "github.com/kaz-as/test-transactions/internal/shards/repository/sqlite"
+	snapshotsrepo "github.com/kaz-as/test-transactions/internal/snapshots/repository/postgres"
+	snapshotssqlite "github.com/kaz-as/test-transactions/internal/snapshots/repository/sqlite"
+	"github.com/kaz-as/test-transactions/internal/sqlite"
+	transactions "github.com/kaz-as/test-transactions/internal/transactions/repository/postgres"
+	transactionssqlite "github.com/kaz-as/test-transactions/internal/transactions/repository/sqlite"
+	"github.com/kaz-as/test-transactions/internal/usecases/general"
+	users "github.com/kaz-as/test-transactions/internal/users/repository/postgres"
+	userssqlite "github.com/kaz-as/test-transactions/internal/users/repository/sqlite"
+	"github.com/kaz-as/test-transactions/pkg/clock"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+// isolationLevels are the levels of the DB transactions by their names in the config.
+var isolationLevels = map[string]sql.IsolationLevel{
+	"read_committed":  sql.LevelReadCommitted,
+	"repeatable_read": sql.LevelRepeatableRead,
+	"serializable":    sql.LevelSerializable,
+}
+
+// timestamps are the Postgres functions of the DB timestamps from the config.
+var timestamps = map[string]transactions.TimestampFunc{
+	config.TimestampsTransaction: transactions.TransactionTimestamp,
+	config.TimestampsStatement:   transactions.StatementTimestamp,
+}
+
+// OpenDB connects to the DB from the config. The tools built alongside the app use it too.
+func OpenDB(cfg config.DB) (*sql.DB, error) {
+	if cfg.Driver == config.DriverSQLite {
+		// a writer waits for another one up to the lock timeout or the timeout of the use case
+		busyTimeout := cfg.Timeout
+		if cfg.LockTimeout > 0 {
+			busyTimeout = cfg.LockTimeout
+		}
+		return sqlite.Open(cfg.Path, busyTimeout)
+	}
+
+	poolCfg, err := pgxpool.ParseConfig(DSN(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("db config: %s", err)
+	}
+
+	poolCfg.MaxConns = int32(cfg.MaxConns)
+	poolCfg.MinConns = int32(cfg.MinConns)
+	poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+
+	// the statements are prepared on the first use by every connection and then run in one round trip
+	poolCfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+	poolCfg.ConnConfig.StatementCacheCapacity = cfg.StatementCache
+	if cfg.StatementCache <= 0 {
+		// e.g. behind PgBouncer in the transaction mode, where the prepared statements are not kept
+		poolCfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("db open: %s", err)
+	}
+
+	// The connections idle in the pool of pgx, which keeps their statement caches. A connection kept idle
+	// by database/sql stays acquired from the pool of pgx, which could then neither close it after
+	// MaxConnIdleTime nor replace it after MaxConnLifetime, so database/sql keeps none: it returns every
+	// connection to the pool of pgx once it is released.
+	db := sql.OpenDB(poolConnector{Connector: stdlib.GetPoolConnector(pool), pool: pool})
+	db.SetMaxIdleConns(0)
+	db.SetMaxOpenConns(cfg.MaxConns)
+
+	err = db.Ping()
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("db ping: %s", err)
+	}
+
+	return db, nil
+}
+
+// poolConnector closes the pool of pgx along with the DB.
+type poolConnector struct {
+	driver.Connector
+	pool *pgxpool.Pool
+}
+
+func (c poolConnector) Close() error {
+	c.pool.Close()
+	return nil
+}
+
+// DSN returns the connection string of the Postgres DB from the config.
+func DSN(cfg config.DB) string {
+	return fmt.Sprintf("postgresql://%s:%s@%s:%s/%s", cfg.User, cfg.Pass, cfg.Host, cfg.Port, cfg.Name)
+}
+
+// NewUseCase wires the use case with the repositories of the driver, the DB settings and the transfer policy
+// from the config. The transactions are stamped with the clock of the host unless the DB timestamps are set;
+// the use case has the same clock.
+func NewUseCase(l logger.Interface, db *sql.DB, cfg *config.Config, opts ...general.Option) (
+	*general.UseCase,
+	error,
+) {
+	rules, err := policy.FromConfig(policyConfig(cfg.Policy))
+	if err != nil {
+		return nil, fmt.Errorf("transfer policy: %w", err)
+	}
+
+	levels, err := isolation(cfg.DB.Isolation)
+	if err != nil {
+		return nil, err
+	}
+
+	clk := clock.System()
+
+	opts = append([]general.Option{
+		general.Clock(clk),
+		general.Policy(rules),
+		general.Retry(general.RetryPolicy{
+			MaxAttempts: cfg.DB.RetryAttempts,
+			BaseDelay:   cfg.DB.RetryBaseDelay,
+			MaxDelay:    cfg.DB.RetryMaxDelay,
+		}),
+		general.PrimaryShards(cfg.DB.PrimaryShards),
+		general.Isolation(levels),
+	}, opts...)
+
+	if cfg.DB.Driver == config.DriverSQLite {
+		return general.NewUseCase(
+			l,
+			db,
+			userssqlite.NewRepo(l),
+			shardssqlite.NewRepo(l),
+			transactionssqlite.NewRepo(l, clk),
+			snapshotssqlite.NewRepo(l),
+			auditsqlite.NewRepo(l),
+			eventssqlite.NewRepo(l),
+			cfg.DB.Timeout,
+			opts...,
+		), nil
+	}
+
+	// SQLite has the busy timeout of the DB instead
+	opts = append([]general.Option{general.Timeouts(general.DBTimeouts{
+		Lock:      cfg.DB.LockTimeout,
+		Statement: cfg.DB.StatementTimeout,
+	})}, opts...)
+
+	var txOpts []transactions.Option
+	if f, ok := timestamps[cfg.DB.Timestamps]; ok {
+		txOpts = append(txOpts, transactions.DBTimestamps(f))
+	}
+
+	return general.NewUseCase(
+		l,
+		db,
+		users.NewRepo(l),
+		shards.NewRepo(l),
+		transactions.NewRepo(l, clk, txOpts...),
+		snapshotsrepo.NewRepo(l),
+		auditrepo.NewRepo(l),
+		eventsrepo.NewRepo(l),
+		cfg.DB.Timeout,
+		opts...,
+	), nil
+}
+
+// policyConfig returns the config of the policy engine.
+func policyConfig(cfg config.Policy) policy.Config {
+	rules := make([]policy.RuleConfig, 0, len(cfg.Rules))
+	for _, rc := range cfg.Rules {
+		rules = append(rules, policy.RuleConfig{Name: rc.Name, Decision: rc.Decision, Limit: rc.Limit, Users: rc.Users})
+	}
+	return policy.Config{Rules: rules}
+}
+
+// isolation returns the isolation levels by operation from the config.
+func isolation(cfg map[string]string) (map[string]sql.IsolationLevel, error) {
+	operations := make(map[string]bool, len(general.Operations))
+	for _, op := range general.Operations {
+		operations[op] = true
+	}
+
+	levels := make(map[string]sql.IsolationLevel, len(cfg))
+	for op, name := range cfg {
+		if !operations[op] {
+			return nil, fmt.Errorf("isolation: unknown operation %q", op)
+		}
+
+		level, ok := isolationLevels[name]
+		if !ok {
+			return nil, fmt.Errorf("isolation of %s: unknown level %q", op, name)
+		}
+
+		levels[op] = level
+	}
+
+	return levels, nil
+}
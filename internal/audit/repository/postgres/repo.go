@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+	"github.com/kaz-as/test-transactions/pkg/tracing"
+)
+
+type auditRepo struct {
+	log logger.Interface
+}
+
+func NewRepo(log logger.Interface) domain.AuditRepository {
+	return &auditRepo{
+		log: log,
+	}
+}
+
+func (a *auditRepo) Store(ctx context.Context, tx *sql.Tx, record *domain.AuditRecord) (err error) {
+	ctx, span := tracing.Start(ctx, "auditRepo.Store")
+	defer tracing.End(span, &err)
+
+	query := `
+INSERT INTO admin_audit (operator, action, reason, params, error)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, at`
+
+	params, err := json.Marshal(record.Params)
+	if err != nil {
+		return fmt.Errorf("marshal params: %w", err)
+	}
+
+	row := tx.QueryRowContext(ctx, query, record.Operator, record.Action, record.Reason, string(params), record.Error)
+
+	err = row.Scan(&record.ID, &record.At)
+	if err != nil {
+		return fmt.Errorf("scan: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,225 @@
+// Package events fans out the balance events published by any app instance to the subscribers of this one.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/kaz-as/test-transactions/domain"
+	eventsrepo "github.com/kaz-as/test-transactions/internal/events/repository/postgres"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+const (
+	subscriptionBuffer = 64
+	minReconnectDelay  = time.Second
+	maxReconnectDelay  = 30 * time.Second
+)
+
+// Broker listens to the Postgres notifications on a dedicated connection, out of the DB pool.
+type Broker struct {
+	log logger.Interface
+	dsn string
+
+	mu   sync.Mutex
+	subs map[domain.UserID]map[*Subscription]struct{}
+
+	cancel   context.CancelFunc
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewBroker(log logger.Interface, dsn string) *Broker {
+	return &Broker{
+		log:  log,
+		dsn:  dsn,
+		subs: make(map[domain.UserID]map[*Subscription]struct{}),
+	}
+}
+
+// Start listens until Stop, reconnecting on errors.
+func (b *Broker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.done = make(chan struct{})
+
+	go func() {
+		defer close(b.done)
+
+		delay := minReconnectDelay
+		for {
+			err := b.listen(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+
+			b.log.Error("balance events: listen failed", "error", err, "retry_in", delay)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+
+			delay = min(delay*2, maxReconnectDelay)
+		}
+	}()
+}
+
+// Stop ends all the subscriptions. It can be called more than once.
+func (b *Broker) Stop() {
+	b.stopOnce.Do(func() {
+		if b.cancel != nil {
+			b.cancel()
+			<-b.done
+		}
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		for _, subs := range b.subs {
+			for s := range subs {
+				close(s.done)
+			}
+		}
+		b.subs = make(map[domain.UserID]map[*Subscription]struct{})
+	})
+}
+
+// Subscribe returns a subscription to the events of the user. It must be closed.
+func (b *Broker) Subscribe(userID domain.UserID) *Subscription {
+	s := &Subscription{
+		broker: b,
+		userID: userID,
+		events: make(chan domain.BalanceEvent, subscriptionBuffer),
+		lost:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[*Subscription]struct{})
+	}
+	b.subs[userID][s] = struct{}{}
+
+	return s
+}
+
+func (b *Broker) unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[s.userID][s]; !ok {
+		return
+	}
+
+	delete(b.subs[s.userID], s)
+	if len(b.subs[s.userID]) == 0 {
+		delete(b.subs, s.userID)
+	}
+}
+
+func (b *Broker) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, b.dsn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close(context.Background())
+	}()
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{eventsrepo.Channel}.Sanitize())
+	if err != nil {
+		return err
+	}
+
+	b.log.Info("balance events: listening")
+
+	// nothing was delivered while the broker was not listening
+	b.loseAll()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		b.dispatch(n.Payload)
+	}
+}
+
+func (b *Broker) dispatch(payload string) {
+	event := domain.BalanceEvent{}
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		b.log.Error("balance events: bad payload", "error", err, "payload", payload)
+		return
+	}
+
+	b.Publish(event)
+}
+
+// Publish delivers the event to the subscribers of this app instance only.
+func (b *Broker) Publish(event domain.BalanceEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.subs[event.UserID] {
+		select {
+		case s.events <- event:
+		default:
+			// a slow subscriber catches up from the DB
+			s.lose()
+		}
+	}
+}
+
+func (b *Broker) loseAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, subs := range b.subs {
+		for s := range subs {
+			s.lose()
+		}
+	}
+}
+
+// Subscription delivers the events of a user. Events may be lost, e.g. if the subscriber is too slow
+// or the broker reconnects; then Lost is signaled and the subscriber should read the missed events from the DB.
+type Subscription struct {
+	broker *Broker
+	userID domain.UserID
+	events chan domain.BalanceEvent
+	lost   chan struct{}
+	done   chan struct{}
+}
+
+func (s *Subscription) Events() <-chan domain.BalanceEvent {
+	return s.events
+}
+
+func (s *Subscription) Lost() <-chan struct{} {
+	return s.lost
+}
+
+// Done is closed when the broker stops.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *Subscription) Close() {
+	s.broker.unsubscribe(s)
+}
+
+func (s *Subscription) lose() {
+	select {
+	case s.lost <- struct{}{}:
+	default:
+	}
+}
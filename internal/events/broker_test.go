@@ -0,0 +1,60 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+func payload(t *testing.T, event domain.BalanceEvent) string {
+	b, err := json.Marshal(event)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestDispatch(t *testing.T) {
+	b := NewBroker(logger.Nop(), "")
+
+	a1 := b.Subscribe("a")
+	a2 := b.Subscribe("a")
+	other := b.Subscribe("b")
+
+	event := domain.BalanceEvent{TxID: "tx1", UserID: "a", Delta: -5, Balance: 95, Timestamp: time.Unix(100, 0).UTC()}
+	b.dispatch(payload(t, event))
+	b.dispatch("not json")
+
+	assert.Equal(t, event, <-a1.Events())
+	assert.Equal(t, event, <-a2.Events())
+	assert.Empty(t, other.Events(), "events of other users must not be delivered")
+
+	a2.Close()
+	b.dispatch(payload(t, event))
+	assert.Len(t, a1.Events(), 1)
+	assert.Empty(t, a2.Events(), "closed subscription must not get events")
+
+	b.Stop()
+	_, open := <-a1.Done()
+	assert.False(t, open, "subscriptions must be done after stop")
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	b := NewBroker(logger.Nop(), "")
+	s := b.Subscribe("a")
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		b.dispatch(payload(t, domain.BalanceEvent{TxID: "tx", UserID: "a"}))
+	}
+
+	assert.Len(t, s.Events(), subscriptionBuffer)
+	select {
+	case <-s.Lost():
+	default:
+		t.Fatal("overflow must be signaled as lost events")
+	}
+}
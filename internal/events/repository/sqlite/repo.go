@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/kaz-as/test-transactions/domain"
+	sqlitedb "github.com/kaz-as/test-transactions/internal/sqlite"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+	"github.com/kaz-as/test-transactions/pkg/tracing"
+)
+
+type eventsRepo struct {
+	log logger.Interface
+}
+
+func NewRepo(log logger.Interface) domain.EventsRepository {
+	return &eventsRepo{
+		log: log,
+	}
+}
+
+// Notify does nothing: SQLite has no notifications, so the events can only be listed.
+func (e *eventsRepo) Notify(context.Context, *sql.Tx, ...domain.BalanceEvent) error {
+	return nil
+}
+
+func (e *eventsRepo) Cursor(ctx context.Context, tx *sql.Tx, userID domain.UserID, txID string) (
+	_ domain.EventCursor,
+	err error,
+) {
+	ctx, span := tracing.Start(ctx, "eventsRepo.Cursor")
+	defer tracing.End(span, &err)
+
+	query := `
+SELECT CASE WHEN "from" = ?2 THEN from_seq ELSE to_seq END
+FROM transactions
+WHERE id = ?1 AND ?2 IN ("from", "to")`
+
+	row := tx.QueryRowContext(ctx, query, txID, string(userID))
+
+	cursor := domain.EventCursor{}
+	err = row.Scan(&cursor.Seq)
+	if err != nil {
+		return cursor, fmt.Errorf("scan: %w", err)
+	}
+
+	return cursor, nil
+}
+
+// ListAfter lists the postings of the user numbered after the cursor, with the balances stored along.
+func (e *eventsRepo) ListAfter(
+	ctx context.Context,
+	tx *sql.Tx,
+	userID domain.UserID,
+	after domain.EventCursor,
+	limit int,
+) (_ []domain.BalanceEvent, err error) {
+	ctx, span := tracing.Start(ctx, "eventsRepo.ListAfter")
+	defer tracing.End(span, &err)
+
+	query := `
+SELECT id, seq, delta, balance, timestamp
+FROM (
+    SELECT id, from_seq AS seq, -value AS delta, from_balance AS balance, timestamp
+    FROM transactions
+    WHERE "from" = ?1 AND from_seq > ?2
+    UNION ALL
+    SELECT id, to_seq, value, to_balance, timestamp
+    FROM transactions
+    WHERE "to" = ?1 AND to_seq > ?2
+) postings
+ORDER BY seq
+LIMIT ?3`
+
+	rows, err := tx.QueryContext(ctx, query, string(userID), after.Seq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			e.log.Ctx(ctx).Error("close rows", "error", err)
+		}
+	}()
+
+	var res []domain.BalanceEvent
+	for rows.Next() {
+		event := domain.BalanceEvent{UserID: userID}
+		var timestamp int64
+		err = rows.Scan(&event.TxID, &event.Seq, (*int64)(&event.Delta), (*int64)(&event.Balance), &timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		event.Timestamp = sqlitedb.Time(timestamp)
+		res = append(res, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,78 @@
+package grpcapi
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/kaz-as/test-transactions/internal/middlewares"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+// RequestIDKey is the metadata key of the request id, the same as the X-Request-ID header of the REST API.
+const RequestIDKey = "x-request-id"
+
+// Interceptors returns the unary interceptors in the order of the REST global middlewares:
+// request id, logging, recovery.
+func Interceptors(l logger.Interface) []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{
+		requestID(),
+		logging(l),
+		recoverer(l),
+	}
+}
+
+func requestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var id string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if ids := md.Get(RequestIDKey); len(ids) > 0 {
+				id = ids[0]
+			}
+		}
+		id = middlewares.ResolveRequestID(id)
+
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, id))
+
+		return handler(logger.ContextWith(ctx, "request_id", id), req)
+	}
+}
+
+func logging(l logger.Interface) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		l.Ctx(ctx).Info("grpc call",
+			"method", info.FullMethod,
+			"code", status.Code(err).String(),
+			"duration", time.Since(start),
+		)
+
+		return resp, err
+	}
+}
+
+func recoverer(l logger.Interface) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (resp interface{}, err error) {
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				l.Ctx(ctx).Error("panic", "method", info.FullMethod, "panic", rvr, "stack", string(debug.Stack()))
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
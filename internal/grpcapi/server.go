@@ -0,0 +1,209 @@
+// Package grpcapi serves the gRPC API on top of the same use case as the REST handlers.
+package grpcapi
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/apierr"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+	txv1 "github.com/kaz-as/test-transactions/proto/tx/v1"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 1000
+)
+
+type server struct {
+	txv1.UnimplementedTxServiceServer
+
+	log logger.Interface
+	uc  domain.UseCase
+}
+
+// Register adds TxService to the gRPC server.
+func Register(s grpc.ServiceRegistrar, log logger.Interface, uc domain.UseCase) {
+	txv1.RegisterTxServiceServer(s, &server{
+		log: log,
+		uc:  uc,
+	})
+}
+
+func (s *server) CreateUser(ctx context.Context, req *txv1.CreateUserRequest) (*txv1.CreateUserResponse, error) {
+	user := domain.User{
+		Balance: domain.Balance(req.GetBalance()),
+	}
+
+	err := s.uc.CreateUser(ctx, &user)
+	if err != nil {
+		return nil, s.error(ctx, "create user failed", err)
+	}
+
+	s.log.Ctx(ctx).Info("create user success", "user_id", user.ID, "balance", req.GetBalance())
+	return &txv1.CreateUserResponse{Id: string(user.ID)}, nil
+}
+
+func (s *server) CreateTx(ctx context.Context, req *txv1.CreateTxRequest) (*txv1.CreateTxResponse, error) {
+	tx := txFromRequest(req)
+
+	newBalanceFrom, newBalanceTo, err := s.uc.CreateTx(ctx, &tx)
+	if err != nil {
+		return nil, s.error(ctx, "create tx failed", err)
+	}
+
+	s.log.Ctx(ctx).Info("create tx success", "tx_id", tx.ID, "from", tx.From, "to", tx.To, "value", tx.Value)
+	return &txv1.CreateTxResponse{
+		Id:             tx.ID,
+		NewBalanceFrom: int64(newBalanceFrom),
+		NewBalanceTo:   int64(newBalanceTo),
+	}, nil
+}
+
+func (s *server) QuoteTx(ctx context.Context, req *txv1.CreateTxRequest) (*txv1.QuoteTxResponse, error) {
+	tx := txFromRequest(req)
+
+	quote, err := s.uc.QuoteTx(ctx, &tx)
+	if err != nil {
+		return nil, s.error(ctx, "quote tx failed", err)
+	}
+
+	res := &txv1.QuoteTxResponse{
+		NewBalanceFrom: int64(quote.NewBalanceFrom),
+		NewBalanceTo:   int64(quote.NewBalanceTo),
+		Fee:            int64(quote.Fee),
+	}
+	for _, v := range quote.Violations {
+		res.Violations = append(res.Violations, &txv1.Violation{
+			Decision: v.Decision,
+			Rule:     v.Rule,
+			Reason:   v.Reason,
+			Message:  v.Message,
+		})
+	}
+
+	return res, nil
+}
+
+func txFromRequest(req *txv1.CreateTxRequest) domain.Tx {
+	tx := domain.Tx{
+		From:              domain.UserID(req.GetFrom()),
+		To:                domain.UserID(req.GetTo()),
+		Value:             domain.Balance(req.GetValue()),
+		Description:       req.GetDescription(),
+		ExternalReference: req.GetExternalReference(),
+	}
+	if req.Metadata != nil {
+		tx.Metadata = req.Metadata.AsMap()
+	}
+	tx.Expect.Version = req.ExpectedVersion
+	if req.ExpectedBalance != nil {
+		balance := domain.Balance(req.GetExpectedBalance())
+		tx.Expect.Balance = &balance
+	}
+
+	return tx
+}
+
+func (s *server) GetBalance(ctx context.Context, req *txv1.GetBalanceRequest) (*txv1.GetBalanceResponse, error) {
+	var at *time.Time
+	if req.At != nil {
+		if err := req.At.CheckValid(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "at: %s", err)
+		}
+		t := req.At.AsTime()
+		at = &t
+	}
+
+	if at == nil {
+		user, err := s.uc.GetUser(ctx, domain.UserID(req.GetId()))
+		if err != nil {
+			return nil, s.error(ctx, "get balance failed", err)
+		}
+
+		return &txv1.GetBalanceResponse{
+			Id:      req.GetId(),
+			Balance: int64(user.Balance),
+			Version: user.Version,
+		}, nil
+	}
+
+	balance, err := s.uc.GetBalance(ctx, domain.UserID(req.GetId()), at)
+	if err != nil {
+		return nil, s.error(ctx, "get balance failed", err)
+	}
+
+	return &txv1.GetBalanceResponse{
+		Id:      req.GetId(),
+		Balance: int64(balance),
+		At:      req.At,
+	}, nil
+}
+
+func (s *server) GetHistory(ctx context.Context, req *txv1.GetHistoryRequest) (*txv1.GetHistoryResponse, error) {
+	limit := int(req.GetLimit())
+	if limit == 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit < 0 || limit > maxHistoryLimit {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be in [1, %d]", maxHistoryLimit)
+	}
+
+	filter := domain.TxFilter{
+		ExternalReference: req.GetExternalReference(),
+		Metadata:          req.GetMetadata(),
+	}
+
+	txs, err := s.uc.History(ctx, domain.UserID(req.GetId()), filter, limit)
+	if err != nil {
+		return nil, s.error(ctx, "get history failed", err)
+	}
+
+	res := &txv1.GetHistoryResponse{
+		Transactions: make([]*txv1.Transaction, 0, len(txs)),
+	}
+	for _, tx := range txs {
+		seq, balance := tx.Posting(domain.UserID(req.GetId()))
+		transaction := &txv1.Transaction{
+			Id:                tx.ID,
+			From:              string(tx.From),
+			To:                string(tx.To),
+			Value:             int64(tx.Value),
+			Timestamp:         timestamppb.New(tx.Timestamp),
+			Description:       tx.Description,
+			ExternalReference: tx.ExternalReference,
+			Seq:               seq,
+			Balance:           int64(balance),
+		}
+		if tx.Metadata != nil {
+			// the metadata is decoded from JSON, so it always fits a Struct
+			transaction.Metadata, err = structpb.NewStruct(tx.Metadata)
+			if err != nil {
+				return nil, s.error(ctx, "get history failed", err)
+			}
+		}
+		res.Transactions = append(res.Transactions, transaction)
+	}
+
+	return res, nil
+}
+
+// error logs the error like the REST handlers do and converts it to a status.
+func (s *server) error(ctx context.Context, msg string, err error) error {
+	code := apierr.GRPCCode(err)
+
+	if !apierr.Public(err) {
+		s.log.Ctx(ctx).Error(msg, "error", err)
+		return status.Error(code, "internal error")
+	}
+
+	s.log.Ctx(ctx).Info(msg, "error", err, "code", code.String())
+	return status.Error(code, err.Error())
+}
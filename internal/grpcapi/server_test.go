@@ -0,0 +1,263 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/usecases/general"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+	txv1 "github.com/kaz-as/test-transactions/proto/tx/v1"
+)
+
+type fakeUseCase struct {
+	err    error
+	at     *time.Time
+	filter domain.TxFilter
+	limit  int
+	panic  bool
+	expect domain.Precondition
+}
+
+func (f *fakeUseCase) CreateUser(_ context.Context, user *domain.User) error {
+	if f.panic {
+		panic("boom")
+	}
+	if f.err != nil {
+		return f.err
+	}
+	user.ID = "u1"
+	return nil
+}
+
+func (f *fakeUseCase) CreateTx(_ context.Context, tx *domain.Tx) (domain.Balance, domain.Balance, error) {
+	f.expect = tx.Expect
+	if f.err != nil {
+		return 0, 0, f.err
+	}
+	tx.ID = "tx1"
+	return 90, 110, nil
+}
+
+func (f *fakeUseCase) QuoteTx(_ context.Context, tx *domain.Tx) (domain.Quote, error) {
+	if f.err != nil {
+		return domain.Quote{}, f.err
+	}
+	if tx.Value > 100 {
+		return domain.Quote{Violations: []domain.Violation{{Decision: "deny", Rule: "insufficient_balance"}}}, nil
+	}
+	return domain.Quote{NewBalanceFrom: 100 - tx.Value, NewBalanceTo: tx.Value}, nil
+}
+
+func (f *fakeUseCase) GetBalance(_ context.Context, _ domain.UserID, at *time.Time) (domain.Balance, error) {
+	f.at = at
+	return 100, f.err
+}
+
+func (f *fakeUseCase) GetUser(_ context.Context, userID domain.UserID) (*domain.User, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &domain.User{ID: userID, Balance: 100, Version: 3}, nil
+}
+
+func (f *fakeUseCase) History(_ context.Context, userID domain.UserID, filter domain.TxFilter, limit int) (
+	[]domain.Tx,
+	error,
+) {
+	f.filter = filter
+	f.limit = limit
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []domain.Tx{
+		{ID: "tx2", From: "other", To: userID, Value: 5, Timestamp: time.Unix(200, 0),
+			ExternalReference: "order-2", Metadata: map[string]interface{}{"order": "2"}, ToSeq: 2, ToBalance: 102},
+		{ID: "tx1", From: userID, To: "other", Value: 3, Timestamp: time.Unix(100, 0)},
+	}, nil
+}
+
+func dial(t *testing.T, uc domain.UseCase) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(Interceptors(logger.Nop())...))
+	Register(srv, logger.Nop(), uc)
+	healthpb.RegisterHealthServer(srv, grpchealth.NewServer())
+	reflection.Register(srv)
+
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return conn
+}
+
+func TestCreate(t *testing.T) {
+	uc := &fakeUseCase{}
+	client := txv1.NewTxServiceClient(dial(t, uc))
+	ctx := context.Background()
+
+	user, err := client.CreateUser(ctx, &txv1.CreateUserRequest{Balance: 10})
+	require.NoError(t, err)
+	assert.Equal(t, "u1", user.GetId())
+
+	tx, err := client.CreateTx(ctx, &txv1.CreateTxRequest{From: "a", To: "b", Value: 10})
+	require.NoError(t, err)
+	assert.Equal(t, "tx1", tx.GetId())
+	assert.Equal(t, int64(90), tx.GetNewBalanceFrom())
+	assert.Equal(t, int64(110), tx.GetNewBalanceTo())
+	assert.Equal(t, domain.Precondition{}, uc.expect)
+
+	version, expected := int64(3), int64(100)
+	_, err = client.CreateTx(ctx, &txv1.CreateTxRequest{
+		From: "a", To: "b", Value: 10, ExpectedVersion: &version, ExpectedBalance: &expected,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, uc.expect.Version)
+	require.NotNil(t, uc.expect.Balance)
+	assert.Equal(t, int64(3), *uc.expect.Version)
+	assert.Equal(t, domain.Balance(100), *uc.expect.Balance)
+
+	quote, err := client.QuoteTx(ctx, &txv1.CreateTxRequest{From: "a", To: "b", Value: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(90), quote.GetNewBalanceFrom())
+	assert.Empty(t, quote.GetViolations())
+
+	quote, err = client.QuoteTx(ctx, &txv1.CreateTxRequest{From: "a", To: "b", Value: 101})
+	require.NoError(t, err)
+	require.Len(t, quote.GetViolations(), 1)
+	assert.Equal(t, "insufficient_balance", quote.GetViolations()[0].GetRule())
+}
+
+func TestReads(t *testing.T) {
+	uc := &fakeUseCase{}
+	client := txv1.NewTxServiceClient(dial(t, uc))
+	ctx := context.Background()
+
+	balance, err := client.GetBalance(ctx, &txv1.GetBalanceRequest{Id: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), balance.GetBalance())
+	assert.Equal(t, int64(3), balance.GetVersion())
+
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	balance, err = client.GetBalance(ctx, &txv1.GetBalanceRequest{Id: "a", At: timestamppb.New(at)})
+	require.NoError(t, err)
+	require.NotNil(t, uc.at)
+	assert.True(t, at.Equal(*uc.at))
+	assert.True(t, at.Equal(balance.GetAt().AsTime()))
+	assert.Zero(t, balance.GetVersion(), "the version is of the current balance only")
+
+	history, err := client.GetHistory(ctx, &txv1.GetHistoryRequest{Id: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, defaultHistoryLimit, uc.limit)
+	require.Len(t, history.GetTransactions(), 2)
+	assert.Equal(t, "tx2", history.GetTransactions()[0].GetId())
+	assert.Equal(t, int64(200), history.GetTransactions()[0].GetTimestamp().GetSeconds())
+	assert.Equal(t, "order-2", history.GetTransactions()[0].GetExternalReference())
+	assert.Equal(t, int64(2), history.GetTransactions()[0].GetSeq())
+	assert.Equal(t, int64(102), history.GetTransactions()[0].GetBalance())
+	assert.Equal(t, map[string]interface{}{"order": "2"}, history.GetTransactions()[0].GetMetadata().AsMap())
+
+	_, err = client.GetHistory(ctx, &txv1.GetHistoryRequest{
+		Id:                "a",
+		ExternalReference: "order-2",
+		Metadata:          map[string]string{"order": "2"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.TxFilter{ExternalReference: "order-2", Metadata: map[string]string{"order": "2"}}, uc.filter)
+
+	_, err = client.GetHistory(ctx, &txv1.GetHistoryRequest{Id: "a", Limit: maxHistoryLimit + 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+		msg  string
+	}{
+		{fmt.Errorf("user id=a: %w", general.ErrUserNotFound), codes.NotFound, "user id=a: user not found"},
+		{general.ErrSame, codes.InvalidArgument, "to = from"},
+		{general.ErrNegativeTx, codes.InvalidArgument, "negative tx"},
+		{general.ErrInsufficientBalance, codes.FailedPrecondition, "insufficient balance"},
+		{general.ErrTooMuch, codes.FailedPrecondition, "too much"},
+		{general.ErrDuplicateReference, codes.AlreadyExists, "duplicate external reference"},
+		{errors.New("db password is wrong"), codes.Internal, "internal error"},
+	}
+
+	for _, tt := range tests {
+		client := txv1.NewTxServiceClient(dial(t, &fakeUseCase{err: tt.err}))
+
+		_, err := client.CreateTx(context.Background(), &txv1.CreateTxRequest{From: "a", To: "b", Value: 1})
+
+		st, ok := status.FromError(err)
+		require.True(t, ok)
+		assert.Equal(t, tt.code, st.Code(), tt.err.Error())
+		assert.Equal(t, tt.msg, st.Message(), "internal details must not leak")
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	client := txv1.NewTxServiceClient(dial(t, &fakeUseCase{panic: true}))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), RequestIDKey, "abc-123")
+
+	var header metadata.MD
+	_, err := client.CreateUser(ctx, &txv1.CreateUserRequest{Balance: 1}, grpc.Header(&header))
+	assert.Equal(t, codes.Internal, status.Code(err), "panic must be recovered")
+	assert.Equal(t, []string{"abc-123"}, header.Get(RequestIDKey))
+}
+
+func TestHealthAndReflection(t *testing.T) {
+	conn := dial(t, &fakeUseCase{})
+	ctx := context.Background()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	res, err := stream.Recv()
+	require.NoError(t, err)
+
+	var services []string
+	for _, s := range res.GetListServicesResponse().GetService() {
+		services = append(services, s.GetName())
+	}
+	assert.Contains(t, services, txv1.TxService_ServiceDesc.ServiceName)
+	assert.Contains(t, services, healthpb.Health_ServiceDesc.ServiceName)
+}
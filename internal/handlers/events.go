@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/apierr"
+	"github.com/kaz-as/test-transactions/internal/events"
+	"github.com/kaz-as/test-transactions/internal/usecases/general"
+	"github.com/kaz-as/test-transactions/models"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+// EventsPattern is the route of the balance events stream. It is served out of the swagger API,
+// since the stream does not fit a swagger operation.
+const EventsPattern = "GET /user/{id}/events"
+
+// EventsRoute names the stream in the request metrics, like the operation ids name the swagger routes.
+const EventsRoute = "balanceEvents"
+
+const (
+	eventsHeartbeat  = 15 * time.Second
+	eventsReplayPage = 500
+)
+
+var userIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// EventsUseCase is the part of general.UseCase the events stream needs.
+type EventsUseCase interface {
+	GetUser(ctx context.Context, userID domain.UserID) (*domain.User, error)
+	EventCursor(ctx context.Context, userID domain.UserID, txID string) (domain.EventCursor, error)
+	BalanceEvents(ctx context.Context, userID domain.UserID, after domain.EventCursor, limit int) (
+		[]domain.BalanceEvent,
+		error,
+	)
+}
+
+var _ EventsUseCase = (*general.UseCase)(nil)
+
+type eventsHandler struct {
+	log    logger.Interface
+	uc     EventsUseCase
+	broker *events.Broker
+}
+
+// Events streams the balance events of the user as Server-Sent Events. The id of an event is the id
+// of its transaction, so a client resumes with Last-Event-ID from the next transaction of the user.
+func Events(log logger.Interface, uc EventsUseCase, broker *events.Broker) http.Handler {
+	return &eventsHandler{
+		log:    log,
+		uc:     uc,
+		broker: broker,
+	}
+}
+
+func (h *eventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := h.log.Ctx(ctx)
+
+	trace.SpanFromContext(ctx).SetName("getEvents")
+
+	userID := domain.UserID(r.PathValue("id"))
+	if !userIDPattern.MatchString(string(userID)) {
+		msg := fmt.Sprintf("id should match %s", userIDPattern)
+		writeError(w, http.StatusUnprocessableEntity, &models.Error{Message: &msg})
+		return
+	}
+
+	// subscribe before reading the DB, so no event is missed in between
+	sub := h.broker.Subscribe(userID)
+	defer sub.Close()
+
+	cursor, err := h.cursor(ctx, userID, r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		code := apierr.HTTPStatus(err)
+		if code >= http.StatusInternalServerError {
+			log.Error("get events failed", "error", err)
+		} else {
+			log.Info("get events failed", "error", err, "code", code)
+		}
+		writeError(w, code, errorPayload(err))
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	if err = rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Error("get events: no streaming support", "error", err)
+		writeError(w, http.StatusInternalServerError, errorPayload(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	s := &stream{w: w, rc: rc, cursor: cursor}
+	if err = s.flush(); err != nil {
+		return
+	}
+
+	// the events after Last-Event-ID, if any
+	if err = h.replay(ctx, userID, s); err != nil {
+		log.Warn("get events: replay failed", "error", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.Done():
+			return
+		case event := <-sub.Events():
+			if s.cursor.Before(event.Cursor()) && !s.cursor.Next(event) {
+				// the events in between are committed, as they are numbered in the order of the commits
+				err = h.replay(ctx, userID, s)
+				break
+			}
+			err = s.send(event)
+		case <-sub.Lost():
+			err = h.replay(ctx, userID, s)
+		case <-heartbeat.C:
+			err = s.ping()
+		}
+
+		if err != nil {
+			log.Info("get events: stream closed", "error", err)
+			return
+		}
+	}
+}
+
+// cursor returns the position of Last-Event-ID, or of the last transaction of the user if there is none
+// or it is unknown.
+func (h *eventsHandler) cursor(ctx context.Context, userID domain.UserID, lastEventID string) (
+	domain.EventCursor,
+	error,
+) {
+	if lastEventID == "" {
+		return h.last(ctx, userID)
+	}
+
+	cursor, err := h.uc.EventCursor(ctx, userID, lastEventID)
+	if errors.Is(err, general.ErrEventNotFound) {
+		h.log.Ctx(ctx).Info("get events: unknown Last-Event-ID", "error", err)
+		return h.last(ctx, userID)
+	}
+
+	return cursor, err
+}
+
+func (h *eventsHandler) last(ctx context.Context, userID domain.UserID) (domain.EventCursor, error) {
+	user, err := h.uc.GetUser(ctx, userID)
+	if err != nil {
+		return domain.EventCursor{}, err
+	}
+
+	return domain.EventCursor{Seq: user.Seq}, nil
+}
+
+func (h *eventsHandler) replay(ctx context.Context, userID domain.UserID, s *stream) error {
+	for {
+		page, err := h.uc.BalanceEvents(ctx, userID, s.cursor, eventsReplayPage)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range page {
+			if err = s.send(event); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < eventsReplayPage {
+			return nil
+		}
+	}
+}
+
+// stream writes the events in order, skipping the ones not after the cursor: an event can come both
+// from the DB and from the broker.
+type stream struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	cursor domain.EventCursor
+}
+
+func (s *stream) send(event domain.BalanceEvent) error {
+	if !s.cursor.Before(event.Cursor()) {
+		return nil
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(s.w, "id: %s\nevent: balance\ndata: %s\n\n", event.TxID, data)
+	if err != nil {
+		return err
+	}
+
+	s.cursor = event.Cursor()
+	return s.flush()
+}
+
+func (s *stream) ping() error {
+	_, err := fmt.Fprint(s.w, ": ping\n\n")
+	if err != nil {
+		return err
+	}
+
+	return s.flush()
+}
+
+func (s *stream) flush() error {
+	return s.rc.Flush()
+}
+
+// writeError answers like the default responses of the swagger API.
+func writeError(w http.ResponseWriter, code int, payload *models.Error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/events"
+	"github.com/kaz-as/test-transactions/internal/usecases/general"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+const testUser = "0123456789abcdef0123456789abcdef"
+
+type fakeEventsUseCase struct {
+	mu     sync.Mutex
+	events []domain.BalanceEvent
+}
+
+func (f *fakeEventsUseCase) add(events ...domain.BalanceEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, events...)
+}
+
+func (f *fakeEventsUseCase) GetUser(_ context.Context, userID domain.UserID) (*domain.User, error) {
+	if userID != testUser {
+		return nil, general.ErrUserNotFound
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &domain.User{ID: userID, Seq: int64(len(f.events))}, nil
+}
+
+func (f *fakeEventsUseCase) EventCursor(_ context.Context, _ domain.UserID, txID string) (domain.EventCursor, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range f.events {
+		if e.TxID == txID {
+			return e.Cursor(), nil
+		}
+	}
+	return domain.EventCursor{}, general.ErrEventNotFound
+}
+
+func (f *fakeEventsUseCase) BalanceEvents(_ context.Context, _ domain.UserID, after domain.EventCursor, limit int) (
+	[]domain.BalanceEvent,
+	error,
+) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var res []domain.BalanceEvent
+	for _, e := range f.events {
+		if after.Before(e.Cursor()) && len(res) < limit {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+func event(n int) domain.BalanceEvent {
+	return domain.BalanceEvent{
+		TxID:      fmt.Sprintf("tx%d", n),
+		UserID:    testUser,
+		Seq:       int64(n),
+		Delta:     1,
+		Balance:   domain.Balance(n),
+		Timestamp: time.Unix(int64(n), 0).UTC(),
+	}
+}
+
+// readIDs reads the ids of n events from the stream.
+func readIDs(t *testing.T, r *bufio.Reader, n int) []string {
+	var ids []string
+	for len(ids) < n {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			ids = append(ids, strings.TrimSpace(id))
+		}
+	}
+	return ids
+}
+
+func serveEvents(t *testing.T, uc EventsUseCase) (*httptest.Server, *events.Broker) {
+	broker := events.NewBroker(logger.Nop(), "")
+	t.Cleanup(broker.Stop)
+
+	mux := http.NewServeMux()
+	mux.Handle(EventsPattern, Events(logger.Nop(), uc, broker))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv, broker
+}
+
+func TestEventsResume(t *testing.T) {
+	uc := &fakeEventsUseCase{events: []domain.BalanceEvent{event(1), event(2), event(3)}}
+	srv, _ := serveEvents(t, uc)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/user/"+testUser+"/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "tx1")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, []string{"tx2", "tx3"}, readIDs(t, bufio.NewReader(resp.Body), 2))
+}
+
+func TestEventsLive(t *testing.T) {
+	uc := &fakeEventsUseCase{}
+	srv, broker := serveEvents(t, uc)
+
+	resp, err := http.Get(srv.URL + "/user/" + testUser + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// the handler has subscribed before the response headers are sent
+	stale := domain.BalanceEvent{TxID: "stale", UserID: testUser, Seq: 0}
+	live := domain.BalanceEvent{TxID: "live", UserID: testUser, Seq: 1}
+
+	// the stale event is before the stream start, so it is skipped
+	broker.Publish(stale)
+	uc.add(live)
+	broker.Publish(live)
+
+	assert.Equal(t, []string{"live"}, readIDs(t, bufio.NewReader(resp.Body), 1))
+}
+
+// TestEventsOutOfOrder commits the transactions in the order other than the one of their timestamps:
+// the events are streamed in the order of the commits.
+func TestEventsOutOfOrder(t *testing.T) {
+	first, second, third := event(1), event(2), event(3)
+	second.Timestamp = first.Timestamp.Add(-time.Hour)
+	third.Timestamp = first.Timestamp.Add(-2 * time.Hour)
+
+	uc := &fakeEventsUseCase{events: []domain.BalanceEvent{first, second}}
+	srv, broker := serveEvents(t, uc)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/user/"+testUser+"/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "tx1")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	r := bufio.NewReader(resp.Body)
+	assert.Equal(t, []string{"tx2"}, readIDs(t, r, 1), "replayed")
+
+	uc.add(third)
+	broker.Publish(third)
+	assert.Equal(t, []string{"tx3"}, readIDs(t, r, 1), "live")
+}
+
+// TestEventsGap replays the events the broker has not delivered, when a later one comes.
+func TestEventsGap(t *testing.T) {
+	uc := &fakeEventsUseCase{}
+	srv, broker := serveEvents(t, uc)
+
+	resp, err := http.Get(srv.URL + "/user/" + testUser + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	uc.add(event(1), event(2))
+	broker.Publish(event(2))
+
+	assert.Equal(t, []string{"tx1", "tx2"}, readIDs(t, bufio.NewReader(resp.Body), 2))
+}
+
+func TestEventsErrors(t *testing.T) {
+	srv, _ := serveEvents(t, &fakeEventsUseCase{})
+
+	resp, err := http.Get(srv.URL + "/user/ffffffffffffffffffffffffffffffff/events")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(srv.URL + "/user/bad/events")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
@@ -4,11 +4,18 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-openapi/loads"
 	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
 
 	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/apierr"
+	"github.com/kaz-as/test-transactions/internal/middlewares"
+	"github.com/kaz-as/test-transactions/internal/usecases/general"
 	"github.com/kaz-as/test-transactions/models"
 	"github.com/kaz-as/test-transactions/pkg/logger"
 	"github.com/kaz-as/test-transactions/restapi"
@@ -35,6 +42,7 @@ func newHandlerSet(
 
 func (s *handlerSet) CreateUserHandler(params operations.CreateUserParams) middleware.Responder {
 	ctx := params.HTTPRequest.Context()
+	log := s.log.Ctx(ctx)
 
 	user := domain.User{
 		Balance: domain.Balance(*params.User.Balance),
@@ -42,30 +50,31 @@ func (s *handlerSet) CreateUserHandler(params operations.CreateUserParams) middl
 
 	err := s.uc.CreateUser(ctx, &user)
 	if err != nil {
-		s.log.Error("create user failed: %s", err)
-		return operations.NewCreateUserDefault(0)
+		code := s.logError(log, "create user failed", err)
+		return operations.NewCreateUserDefault(code).WithPayload(errorPayload(err))
 	}
 
 	userID := string(user.ID)
 
 	ret := operations.NewCreateUserOK().WithPayload(&models.CreateUserSuccess{ID: &userID})
-	s.log.Info("create user success: %s: %d", userID, *params.User.Balance)
+	log.Info("create user success", "user_id", userID, "balance", *params.User.Balance)
 	return ret
 }
 
 func (s *handlerSet) CreateTxHandler(params operations.CreateTxParams) middleware.Responder {
 	ctx := params.HTTPRequest.Context()
+	log := s.log.Ctx(ctx)
 
-	tx := domain.Tx{
-		From:  domain.UserID(*params.Tx.From),
-		To:    domain.UserID(*params.Tx.To),
-		Value: domain.Balance(*params.Tx.Value),
+	tx := txFromModel(params.Tx)
+	if err := expectVersion(&tx, params.IfMatch); err != nil {
+		code := s.logError(log, "create tx failed", err)
+		return operations.NewCreateTxDefault(code).WithPayload(errorPayload(err))
 	}
 
 	newBalanceFrom, newBalanceTo, err := s.uc.CreateTx(ctx, &tx)
 	if err != nil {
-		s.log.Error("create tx failed: %s", err)
-		return operations.NewCreateTxDefault(0)
+		code := s.logError(log, "create tx failed", err)
+		return operations.NewCreateTxDefault(code).WithPayload(errorPayload(err))
 	}
 
 	ret := operations.NewCreateTxOK().WithPayload(&models.CreateTxSuccess{
@@ -73,14 +82,156 @@ func (s *handlerSet) CreateTxHandler(params operations.CreateTxParams) middlewar
 		NewBalanceTo:   (*int64)(&newBalanceTo),
 	})
 
-	s.log.Info("create tx success: %s->%s: %d", *params.Tx.From, *params.Tx.To, *params.Tx.Value)
+	log.Info("create tx success", "tx_id", tx.ID, "from", tx.From, "to", tx.To, "value", tx.Value)
 	return ret
 }
 
+func (s *handlerSet) QuoteTxHandler(params operations.QuoteTxParams) middleware.Responder {
+	ctx := params.HTTPRequest.Context()
+	log := s.log.Ctx(ctx)
+
+	tx := txFromModel(params.Tx)
+	if err := expectVersion(&tx, params.IfMatch); err != nil {
+		code := s.logError(log, "quote tx failed", err)
+		return operations.NewQuoteTxDefault(code).WithPayload(errorPayload(err))
+	}
+
+	quote, err := s.uc.QuoteTx(ctx, &tx)
+	if err != nil {
+		code := s.logError(log, "quote tx failed", err)
+		return operations.NewQuoteTxDefault(code).WithPayload(errorPayload(err))
+	}
+
+	payload := &models.TxQuote{Fee: (*int64)(&quote.Fee)}
+	for i := range quote.Violations {
+		v := &quote.Violations[i]
+		payload.Violations = append(payload.Violations, &models.Violation{
+			Decision: &v.Decision,
+			Rule:     &v.Rule,
+			Reason:   &v.Reason,
+			Message:  &v.Message,
+		})
+	}
+	if len(quote.Violations) == 0 {
+		payload.NewBalanceFrom = (*int64)(&quote.NewBalanceFrom)
+		payload.NewBalanceTo = (*int64)(&quote.NewBalanceTo)
+	}
+
+	return operations.NewQuoteTxOK().WithPayload(payload)
+}
+
+func txFromModel(tx *models.Tx) domain.Tx {
+	ret := domain.Tx{
+		From:              domain.UserID(*tx.From),
+		To:                domain.UserID(*tx.To),
+		Value:             domain.Balance(*tx.Value),
+		Description:       tx.Description,
+		ExternalReference: tx.ExternalReference,
+		Metadata:          tx.Metadata,
+	}
+	if tx.ExpectedBalance != nil {
+		balance := domain.Balance(*tx.ExpectedBalance)
+		ret.Expect.Balance = &balance
+	}
+
+	return ret
+}
+
+// expectVersion makes the transfer conditional on the sender version from the If-Match header. If-Match compares
+// the ETags strongly, so a weak or a malformed ETag never matches, like a stale one.
+func expectVersion(tx *domain.Tx, ifMatch *string) error {
+	if ifMatch == nil || *ifMatch == "*" {
+		return nil
+	}
+
+	etag := *ifMatch
+	if len(etag) < 2 || !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		return fmt.Errorf("if-match %s is not an etag of a balance: %w", etag, general.ErrPreconditionFailed)
+	}
+
+	version, err := strconv.ParseInt(etag[1:len(etag)-1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("if-match %s is not an etag of a balance: %w", etag, general.ErrPreconditionFailed)
+	}
+
+	tx.Expect.Version = &version
+	return nil
+}
+
+// etag is the ETag of a balance with the version.
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+func (s *handlerSet) GetBalanceHandler(params operations.GetBalanceParams) middleware.Responder {
+	ctx := params.HTTPRequest.Context()
+	log := s.log.Ctx(ctx)
+
+	var at *time.Time
+	if params.At != nil {
+		t := time.Time(*params.At)
+		at = &t
+	}
+
+	if at == nil {
+		user, err := s.uc.GetUser(ctx, domain.UserID(params.ID))
+		if err != nil {
+			code := s.logError(log, "get balance failed", err)
+			return operations.NewGetBalanceDefault(code).WithPayload(errorPayload(err))
+		}
+
+		ret := operations.NewGetBalanceOK().WithPayload(&models.UserBalance{
+			ID:      &params.ID,
+			Balance: (*int64)(&user.Balance),
+		})
+		if user.Version > 0 {
+			ret.SetETag(etag(user.Version))
+		}
+
+		return ret
+	}
+
+	balance, err := s.uc.GetBalance(ctx, domain.UserID(params.ID), at)
+	if err != nil {
+		code := s.logError(log, "get balance failed", err)
+		return operations.NewGetBalanceDefault(code).WithPayload(errorPayload(err))
+	}
+
+	return operations.NewGetBalanceOK().WithPayload(&models.UserBalance{
+		ID:      &params.ID,
+		Balance: (*int64)(&balance),
+		At:      strfmt.DateTime(*at),
+	})
+}
+
+// logError logs the errors caused by the client at info level and returns the status code of the error.
+func (s *handlerSet) logError(log logger.Interface, msg string, err error) int {
+	code := apierr.HTTPStatus(err)
+	if code >= http.StatusInternalServerError {
+		log.Error(msg, "error", err)
+	} else {
+		log.Info(msg, "error", err, "code", code)
+	}
+
+	return code
+}
+
+// errorPayload hides the details of the errors the client cannot fix.
+func errorPayload(err error) *models.Error {
+	msg := http.StatusText(http.StatusInternalServerError)
+	if apierr.Public(err) {
+		msg = err.Error()
+	}
+
+	return &models.Error{Message: &msg}
+}
+
+// New returns the API handler. The local middleware is applied to the operations after their routes are matched.
 func New(
 	log logger.Interface,
 	db *sql.DB,
 	uc domain.UseCase,
+	local middlewares.Middleware,
 ) (http.Handler, error) {
 	swaggerDoc, err := loads.Embedded(restapi.SwaggerJSON, restapi.FlatSwaggerJSON)
 	if err != nil {
@@ -88,13 +239,15 @@ func New(
 	}
 
 	api := operations.NewTxAPI(swaggerDoc)
-	api.Logger = log.Info
+	api.Logger = logger.Printf(log.Info)
 	api.UseSwaggerUI()
 
 	hSet := newHandlerSet(log, db, uc)
 
 	api.CreateUserHandler = operations.CreateUserHandlerFunc(hSet.CreateUserHandler)
 	api.CreateTxHandler = operations.CreateTxHandlerFunc(hSet.CreateTxHandler)
+	api.GetBalanceHandler = operations.GetBalanceHandlerFunc(hSet.GetBalanceHandler)
+	api.QuoteTxHandler = operations.QuoteTxHandlerFunc(hSet.QuoteTxHandler)
 
-	return api.Serve(middleware.Builder(nil)), nil
+	return api.Serve(middleware.Builder(local)), nil
 }
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/middlewares"
+	"github.com/kaz-as/test-transactions/internal/usecases/queued"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+// blockingUseCase holds the transfers until release is closed.
+type blockingUseCase struct {
+	domain.UseCase
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingUseCase) CreateTx(context.Context, *domain.Tx) (domain.Balance, domain.Balance, error) {
+	b.started <- struct{}{}
+	<-b.release
+	return 90, 110, nil
+}
+
+func TestCreateTxQueueFull(t *testing.T) {
+	uc := &blockingUseCase{started: make(chan struct{}, 1), release: make(chan struct{})}
+	h, err := New(logger.Nop(), nil, queued.NewUseCase(uc, 0), func(h http.Handler) http.Handler { return h })
+	require.NoError(t, err)
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	post := func() *http.Response {
+		body := `{"from":"` + testUser + `","to":"ffffffffffffffffffffffffffffffff","value":10}`
+		resp, err := http.Post(srv.URL+"/tx", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		return resp
+	}
+
+	done := make(chan *http.Response)
+	go func() { done <- post() }()
+	<-uc.started
+
+	resp := post()
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, "the queue of the account is full")
+
+	var payload struct{ Message string }
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&payload))
+	assert.Equal(t, "user id="+testUser+": too many requests queued for the account", payload.Message)
+
+	close(uc.release)
+	first := <-done
+	_ = first.Body.Close()
+	assert.Equal(t, http.StatusOK, first.StatusCode)
+}
+
+type routesObserver struct {
+	routes []string
+}
+
+func (o *routesObserver) ObserveRequest(operation string, code int, _ time.Duration) {
+	o.routes = append(o.routes, fmt.Sprintf("%s %d", operation, code))
+}
+
+func TestMetricsRoutes(t *testing.T) {
+	h, err := New(logger.Nop(), nil, &blockingUseCase{}, middlewares.SwaggerRoute())
+	require.NoError(t, err)
+	o := &routesObserver{}
+	srv := httptest.NewServer(middlewares.Metrics(o)(h))
+	t.Cleanup(srv.Close)
+
+	for _, path := range []string{"/tx", "/nowhere"} {
+		resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(`{}`))
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+
+	assert.Equal(t, []string{"createTx 422", "unknown 404"}, o.routes)
+}
@@ -0,0 +1,117 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+// Checker serves liveness and readiness probes.
+type Checker struct {
+	log             logger.Interface
+	db              *sql.DB
+	timeout         time.Duration
+	expectedVersion int64
+
+	shuttingDown atomic.Bool
+}
+
+func New(log logger.Interface, db *sql.DB, timeout time.Duration, expectedVersion int64) *Checker {
+	return &Checker{
+		log:             log,
+		db:              db,
+		timeout:         timeout,
+		expectedVersion: expectedVersion,
+	}
+}
+
+// ShutDown makes the app not ready, so no new traffic is routed to it while it is shutting down.
+func (c *Checker) ShutDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Liveness reports that the process is able to serve requests.
+func (c *Checker) Liveness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		c.respond(w, nil)
+	})
+}
+
+// Readiness reports that the app is not shutting down, the DB is reachable and its schema is not behind the app.
+func (c *Checker) Readiness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), c.timeout)
+		defer cancel()
+
+		err := c.ready(ctx)
+		if err != nil {
+			c.log.Ctx(r.Context()).Warn("not ready", "error", err)
+		}
+
+		c.respond(w, err)
+	})
+}
+
+func (c *Checker) ready(ctx context.Context) error {
+	if c.shuttingDown.Load() {
+		return fmt.Errorf("shutting down")
+	}
+
+	if err := c.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("db ping: %w", err)
+	}
+
+	version, err := c.migrationVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("migration version: %w", err)
+	}
+
+	// newer schema is allowed for the old instances to keep serving during a rolling update
+	if version < c.expectedVersion {
+		return fmt.Errorf("migration version %d, expected %d", version, c.expectedVersion)
+	}
+
+	return nil
+}
+
+// migrationVersion returns the latest applied goose migration: the last record for every version decides.
+// The query is run on both Postgres and SQLite.
+func (c *Checker) migrationVersion(ctx context.Context) (int64, error) {
+	query := `
+SELECT COALESCE(MAX(v.version_id), 0)
+FROM goose_db_version v
+WHERE v.is_applied
+  AND v.id = (SELECT MAX(l.id) FROM goose_db_version l WHERE l.version_id = v.version_id)`
+
+	var version int64
+	err := c.db.QueryRowContext(ctx, query).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("scan: %w", err)
+	}
+
+	return version, nil
+}
+
+func (c *Checker) respond(w http.ResponseWriter, err error) {
+	body := map[string]string{"status": "ok"}
+	status := http.StatusOK
+
+	if err != nil {
+		body = map[string]string{"status": "fail", "error": err.Error()}
+		status = http.StatusServiceUnavailable
+	}
+
+	jsonBody, _ := json.Marshal(body)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(jsonBody); err != nil {
+		c.log.Error("cannot write health response", "error", err)
+	}
+}
@@ -0,0 +1,34 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+func TestLiveness(t *testing.T) {
+	c := New(logger.Nop(), nil, 0, 0)
+	c.ShutDown()
+
+	w := httptest.NewRecorder()
+	c.Liveness().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code, "liveness does not depend on shutdown")
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestReadinessShuttingDown(t *testing.T) {
+	// no DB: the shutdown must be checked before anything else
+	c := New(logger.Nop(), nil, 0, 0)
+	c.ShutDown()
+
+	w := httptest.NewRecorder()
+	c.Readiness().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"status":"fail","error":"shutting down"}`, w.Body.String())
+}
@@ -0,0 +1,513 @@
+// Package importer replays JSONL files of user and tx operations through the use case.
+//
+// Every input line is an operation:
+//
+//	{"op": "create_user", "balance": 100, "ref": "alice"}
+//	{"op": "create_tx", "from": "<user id>", "to_ref": "alice", "value": 10}
+//
+// A user created by an earlier line can be referred to by its ref instead of its id.
+// Every line gets a result line with its number, so the results are also the checkpoint of a resumed import.
+package importer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/metrics"
+	"github.com/kaz-as/test-transactions/internal/usecases/general"
+	"github.com/kaz-as/test-transactions/internal/usecases/instrumented"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+// Operations.
+const (
+	OpCreateUser = "create_user"
+	OpCreateTx   = "create_tx"
+)
+
+// Result statuses.
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// Error codes besides the transfer outcomes of the metrics.
+const (
+	CodeBadLine      = "bad_line"
+	CodeUnknownRef   = "unknown_ref"
+	CodeUserNotFound = "user_not_found"
+	// CodeInterrupted is a line that was started before a crash, but has no result: it is not run again,
+	// since it could have been committed.
+	CodeInterrupted = "interrupted"
+)
+
+const maxLineSize = 1 << 20
+
+var ErrStopped = errors.New("import stopped on error")
+
+// Op is an input line.
+type Op struct {
+	Op      string `json:"op"`
+	Ref     string `json:"ref,omitempty"`
+	Balance *int64 `json:"balance,omitempty"`
+	From    string `json:"from,omitempty"`
+	FromRef string `json:"from_ref,omitempty"`
+	To      string `json:"to,omitempty"`
+	ToRef   string `json:"to_ref,omitempty"`
+	Value   *int64 `json:"value,omitempty"`
+
+	Description       string                 `json:"description,omitempty"`
+	ExternalReference string                 `json:"external_reference,omitempty"`
+	Metadata          map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Result is an output line.
+type Result struct {
+	Line   int    `json:"line"`
+	Op     string `json:"op,omitempty"`
+	Status string `json:"status"`
+	ID     string `json:"id,omitempty"`
+	Ref    string `json:"ref,omitempty"`
+	Code   string `json:"code,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type UseCase interface {
+	CreateUser(ctx context.Context, user *domain.User) error
+	CreateTx(ctx context.Context, tx *domain.Tx) (newBalanceFrom domain.Balance, newBalanceTo domain.Balance, err error)
+}
+
+type Options struct {
+	// Concurrency is the number of lines run at once; 1 keeps the order of the file.
+	Concurrency int
+	// ContinueOnError runs the rest of the file after a failed line instead of stopping.
+	ContinueOnError bool
+}
+
+// Summary counts the lines of a run; the lines done by a previous run are skipped.
+type Summary struct {
+	OK      int
+	Failed  int
+	Skipped int
+}
+
+type Importer struct {
+	log  logger.Interface
+	uc   UseCase
+	opts Options
+}
+
+func New(log logger.Interface, uc UseCase, opts Options) *Importer {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+
+	return &Importer{
+		log:  log,
+		uc:   uc,
+		opts: opts,
+	}
+}
+
+// State is what a previous run has done.
+type State struct {
+	done    map[int]Result
+	started map[int]struct{}
+}
+
+// NewState returns the state of a new import.
+func NewState() *State {
+	return &State{
+		done:    make(map[int]Result),
+		started: make(map[int]struct{}),
+	}
+}
+
+// LoadState reads the results and the checkpoint journal of a previous run. A torn last line of a crashed run
+// is ignored. The lines failed for sure are run again, but not the ones that could have been committed,
+// e.g. interrupted or failed with an internal error.
+func LoadState(results, checkpoint io.Reader) (*State, error) {
+	s := NewState()
+
+	sc := bufio.NewScanner(results)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for sc.Scan() {
+		r := Result{}
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil || r.Line == 0 {
+			continue
+		}
+		s.done[r.Line] = r
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read results: %w", err)
+	}
+
+	sc = bufio.NewScanner(checkpoint)
+	for sc.Scan() {
+		n, err := strconv.Atoi(strings.TrimSpace(sc.Text()))
+		if err != nil {
+			continue
+		}
+		s.started[n] = struct{}{}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read checkpoint: %w", err)
+	}
+
+	for line, r := range s.done {
+		if r.Status != StatusOK && r.Code != CodeInterrupted && r.Code != metrics.OutcomeError {
+			delete(s.done, line)
+			delete(s.started, line)
+		}
+	}
+
+	return s, nil
+}
+
+// ref is a user created by a line; it is resolved once the line is done.
+type ref struct {
+	ready chan struct{}
+	id    domain.UserID
+	err   error
+}
+
+func (r *ref) resolve(id domain.UserID, err error) {
+	r.id, r.err = id, err
+	close(r.ready)
+}
+
+type job struct {
+	line     int
+	op       Op
+	ref      *ref
+	from, to *ref
+}
+
+type run struct {
+	*Importer
+
+	refs map[string]*ref
+
+	mu         sync.Mutex
+	results    io.Writer
+	checkpoint io.Writer
+	summary    Summary
+	writeErr   error
+
+	stopped atomic.Bool
+}
+
+// Run imports the lines of in, writing a result per line to results. Before a line is run, its number
+// is written to checkpoint. The lines done or started according to state are not run again.
+func (im *Importer) Run(ctx context.Context, in io.Reader, results, checkpoint io.Writer, state *State) (
+	Summary,
+	error,
+) {
+	r := &run{
+		Importer:   im,
+		refs:       make(map[string]*ref),
+		results:    results,
+		checkpoint: checkpoint,
+	}
+
+	for _, res := range state.done {
+		if res.Op == OpCreateUser && res.Ref != "" {
+			rf := &ref{ready: make(chan struct{})}
+			var err error
+			if res.Status != StatusOK {
+				err = fmt.Errorf("line %d failed: %s", res.Line, res.Code)
+			}
+			rf.resolve(domain.UserID(res.ID), err)
+			r.refs[res.Ref] = rf
+		}
+	}
+
+	jobs := make(chan job)
+	wg := sync.WaitGroup{}
+	for i := 0; i < im.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				// a line queued before another one failed is not run, as it is not journaled yet
+				if r.stopped.Load() {
+					if j.ref != nil {
+						j.ref.resolve("", ErrStopped)
+					}
+					continue
+				}
+				r.do(ctx, j)
+			}
+		}()
+	}
+
+	readErr := r.read(ctx, in, state, jobs)
+	close(jobs)
+	wg.Wait()
+
+	switch {
+	case readErr != nil:
+		return r.summary, readErr
+	case r.writeErr != nil:
+		return r.summary, r.writeErr
+	case ctx.Err() != nil:
+		return r.summary, ctx.Err()
+	case r.stopped.Load():
+		return r.summary, ErrStopped
+	}
+
+	return r.summary, nil
+}
+
+func (r *run) read(ctx context.Context, in io.Reader, state *State, jobs chan<- job) error {
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	for line := 1; sc.Scan(); line++ {
+		if r.stopped.Load() || ctx.Err() != nil {
+			return nil
+		}
+
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+
+		if _, ok := state.done[line]; ok {
+			r.mu.Lock()
+			r.summary.Skipped++
+			r.mu.Unlock()
+			continue
+		}
+
+		op := Op{}
+		err := json.Unmarshal([]byte(text), &op)
+		if err == nil {
+			err = validate(op)
+		}
+
+		var rf *ref
+		if err == nil && op.Op == OpCreateUser && op.Ref != "" {
+			if _, ok := r.refs[op.Ref]; ok {
+				err = fmt.Errorf("duplicate ref %q", op.Ref)
+			} else {
+				rf = &ref{ready: make(chan struct{})}
+				r.refs[op.Ref] = rf
+			}
+		}
+
+		if err != nil {
+			r.finish(Result{Line: line, Op: op.Op, Status: StatusError, Code: CodeBadLine, Error: err.Error()})
+			continue
+		}
+
+		if _, ok := state.started[line]; ok {
+			err = errors.New("started by a previous run, but has no result")
+			if rf != nil {
+				rf.resolve("", err)
+			}
+			r.finish(Result{Line: line, Op: op.Op, Ref: op.Ref, Status: StatusError, Code: CodeInterrupted, Error: err.Error()})
+			continue
+		}
+
+		// the refs are taken here, since the workers must not read the map while it grows
+		j := job{line: line, op: op, ref: rf}
+		for _, name := range []string{op.FromRef, op.ToRef} {
+			if _, ok := r.refs[name]; name != "" && !ok {
+				err = fmt.Errorf("ref %q is not created by an earlier line", name)
+			}
+		}
+		j.from, j.to = r.refs[op.FromRef], r.refs[op.ToRef]
+		if err != nil {
+			r.finish(Result{Line: line, Op: op.Op, Status: StatusError, Code: CodeUnknownRef, Error: err.Error()})
+			continue
+		}
+
+		select {
+		case jobs <- j:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("read input: %w", err)
+	}
+
+	return nil
+}
+
+func validate(op Op) error {
+	switch op.Op {
+	case OpCreateUser:
+		if op.Balance == nil {
+			return errors.New("balance is required")
+		}
+	case OpCreateTx:
+		if (op.From == "") == (op.FromRef == "") {
+			return errors.New("exactly one of from and from_ref is required")
+		}
+		if (op.To == "") == (op.ToRef == "") {
+			return errors.New("exactly one of to and to_ref is required")
+		}
+		if op.Value == nil {
+			return errors.New("value is required")
+		}
+		if op.Ref != "" {
+			return errors.New("ref is only for create_user")
+		}
+	default:
+		return fmt.Errorf("unknown op %q", op.Op)
+	}
+
+	return nil
+}
+
+func (r *run) do(ctx context.Context, j job) {
+	res := Result{Line: j.line, Op: j.op.Op, Ref: j.op.Ref}
+
+	if err := r.start(j.line); err != nil {
+		if j.ref != nil {
+			j.ref.resolve("", err)
+		}
+		return
+	}
+
+	switch j.op.Op {
+	case OpCreateUser:
+		user := domain.User{Balance: domain.Balance(*j.op.Balance)}
+
+		err := r.uc.CreateUser(ctx, &user)
+		if j.ref != nil {
+			j.ref.resolve(user.ID, err)
+		}
+
+		res.setError(err)
+		res.ID = string(user.ID)
+
+	case OpCreateTx:
+		from, err := user(ctx, j.op.From, j.op.FromRef, j.from)
+		if err != nil {
+			res.setRefError(err)
+			break
+		}
+		to, err := user(ctx, j.op.To, j.op.ToRef, j.to)
+		if err != nil {
+			res.setRefError(err)
+			break
+		}
+
+		tx := domain.Tx{
+			From:              from,
+			To:                to,
+			Value:             domain.Balance(*j.op.Value),
+			Description:       j.op.Description,
+			ExternalReference: j.op.ExternalReference,
+			Metadata:          j.op.Metadata,
+		}
+
+		_, _, err = r.uc.CreateTx(ctx, &tx)
+		res.setError(err)
+		res.ID = tx.ID
+	}
+
+	if res.Status != StatusOK {
+		res.ID = ""
+		r.log.Warn("import: line failed", "line", j.line, "code", res.Code, "error", res.Error)
+	}
+
+	r.finish(res)
+}
+
+// user waits for the line creating the ref to be done.
+func user(ctx context.Context, id, name string, rf *ref) (domain.UserID, error) {
+	if rf == nil {
+		return domain.UserID(id), nil
+	}
+
+	select {
+	case <-rf.ready:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	if rf.err != nil {
+		return "", fmt.Errorf("ref %q: %w", name, rf.err)
+	}
+
+	return rf.id, nil
+}
+
+func (res *Result) setError(err error) {
+	if err == nil {
+		res.Status = StatusOK
+		return
+	}
+
+	res.Status = StatusError
+	res.Error = err.Error()
+	res.Code = instrumented.Outcome(err)
+	if errors.Is(err, general.ErrUserNotFound) {
+		res.Code = CodeUserNotFound
+	}
+}
+
+func (res *Result) setRefError(err error) {
+	res.Status = StatusError
+	res.Code = CodeUnknownRef
+	res.Error = err.Error()
+}
+
+// start journals the line before it is run, so a resumed import does not run it twice.
+func (r *run) start(line int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.writeErr != nil {
+		return r.writeErr
+	}
+
+	_, err := fmt.Fprintln(r.checkpoint, line)
+	if err != nil {
+		r.writeErr = fmt.Errorf("write checkpoint: %w", err)
+		r.stopped.Store(true)
+	}
+
+	return r.writeErr
+}
+
+func (r *run) finish(res Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if res.Status == StatusOK {
+		r.summary.OK++
+	} else {
+		r.summary.Failed++
+		if !r.opts.ContinueOnError {
+			r.stopped.Store(true)
+		}
+	}
+
+	if r.writeErr != nil {
+		return
+	}
+
+	b, err := json.Marshal(res)
+	if err == nil {
+		_, err = r.results.Write(append(b, '\n'))
+	}
+	if err != nil {
+		r.writeErr = fmt.Errorf("write results: %w", err)
+		r.stopped.Store(true)
+	}
+}
@@ -0,0 +1,153 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/usecases/general"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+type fakeUseCase struct {
+	mu    sync.Mutex
+	users int
+	txs   []domain.Tx
+}
+
+func (f *fakeUseCase) CreateUser(_ context.Context, user *domain.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.users++
+	user.ID = domain.UserID(fmt.Sprintf("u%d", f.users))
+	return nil
+}
+
+func (f *fakeUseCase) CreateTx(_ context.Context, tx *domain.Tx) (domain.Balance, domain.Balance, error) {
+	if tx.Value > 100 {
+		return 0, 0, general.ErrInsufficientBalance
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.txs = append(f.txs, *tx)
+	tx.ID = fmt.Sprintf("tx%d", len(f.txs))
+	return 0, 0, nil
+}
+
+func results(t *testing.T, out *bytes.Buffer) []Result {
+	var res []Result
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		r := Result{}
+		require.NoError(t, json.Unmarshal([]byte(line), &r))
+		res = append(res, r)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Line < res[j].Line })
+	return res
+}
+
+const input = `{"op": "create_user", "balance": 100, "ref": "a"}
+{"op": "create_user", "balance": 100, "ref": "b"}
+
+{"op": "create_tx", "from_ref": "a", "to_ref": "b", "value": 10}
+{"op": "create_tx", "from_ref": "a", "to": "x", "value": 1000}
+{"op": "create_tx", "from_ref": "c", "to_ref": "b", "value": 1}
+not json
+{"op": "create_tx", "from": "x", "to_ref": "a", "value": 5}
+`
+
+func TestRun(t *testing.T) {
+	uc := &fakeUseCase{}
+	out, checkpoint := &bytes.Buffer{}, &bytes.Buffer{}
+
+	im := New(logger.Nop(), uc, Options{Concurrency: 4, ContinueOnError: true})
+
+	summary, err := im.Run(context.Background(), strings.NewReader(input), out, checkpoint, NewState())
+	require.NoError(t, err)
+	assert.Equal(t, Summary{OK: 4, Failed: 3}, summary)
+
+	res := results(t, out)
+	require.Len(t, res, 7)
+	assert.Equal(t, Result{Line: 1, Op: OpCreateUser, Status: StatusOK, ID: res[0].ID, Ref: "a"}, res[0])
+	assert.Equal(t, StatusOK, res[2].Status)
+	assert.Equal(t, "insufficient_balance", res[3].Code)
+	assert.Equal(t, CodeUnknownRef, res[4].Code)
+	assert.Equal(t, CodeBadLine, res[5].Code)
+	assert.Equal(t, 8, res[6].Line)
+
+	ids := map[string]domain.UserID{"a": domain.UserID(res[0].ID), "b": domain.UserID(res[1].ID)}
+	assert.ElementsMatch(t, []domain.Tx{
+		{ID: "", From: ids["a"], To: ids["b"], Value: 10},
+		{ID: "", From: "x", To: ids["a"], Value: 5},
+	}, withoutIDs(uc.txs), "refs must be resolved to the created users")
+}
+
+func withoutIDs(txs []domain.Tx) []domain.Tx {
+	res := make([]domain.Tx, len(txs))
+	for i, tx := range txs {
+		tx.ID = ""
+		res[i] = tx
+	}
+	return res
+}
+
+func TestStopAndResume(t *testing.T) {
+	const input = `{"op": "create_user", "balance": 100, "ref": "a"}
+{"op": "create_user", "balance": 100, "ref": "b"}
+{"op": "create_tx", "from_ref": "a", "to_ref": "b", "value": 1000}
+{"op": "create_tx", "from_ref": "b", "to_ref": "a", "value": 10}
+`
+
+	uc := &fakeUseCase{}
+	out, checkpoint := &bytes.Buffer{}, &bytes.Buffer{}
+
+	im := New(logger.Nop(), uc, Options{})
+
+	summary, err := im.Run(context.Background(), strings.NewReader(input), out, checkpoint, NewState())
+	assert.ErrorIs(t, err, ErrStopped)
+	assert.Equal(t, Summary{OK: 2, Failed: 1}, summary, "the lines after the failed one must not run")
+	assert.Empty(t, uc.txs)
+
+	fixed := strings.Replace(input, `"value": 1000`, `"value": 1`, 1)
+
+	state, err := LoadState(bytes.NewReader(out.Bytes()), bytes.NewReader(checkpoint.Bytes()))
+	require.NoError(t, err)
+
+	out.Reset()
+	summary, err = im.Run(context.Background(), strings.NewReader(fixed), out, checkpoint, state)
+	require.NoError(t, err)
+	assert.Equal(t, Summary{OK: 2, Skipped: 2}, summary, "the failed line must be run again")
+
+	ids := map[string]domain.UserID{"a": "u1", "b": "u2"}
+	assert.Equal(t, []domain.Tx{
+		{ID: "", From: ids["a"], To: ids["b"], Value: 1},
+		{ID: "", From: ids["b"], To: ids["a"], Value: 10},
+	}, withoutIDs(uc.txs), "refs must be restored from the results")
+
+	// line 2 is lost in a crash after it was started
+	state, err = LoadState(strings.NewReader(`{"line": 1, "op": "create_user", "status": "ok", "id": "u1", "ref": "a"}`),
+		strings.NewReader("1\n2\n"))
+	require.NoError(t, err)
+
+	out.Reset()
+	summary, err = New(logger.Nop(), uc, Options{ContinueOnError: true}).
+		Run(context.Background(), strings.NewReader(input), out, &bytes.Buffer{}, state)
+	require.NoError(t, err)
+
+	res := results(t, out)
+	require.Len(t, res, 3)
+	assert.Equal(t, CodeInterrupted, res[0].Code, "a started line without result must not be run again")
+	assert.Equal(t, CodeUnknownRef, res[1].Code, "the ref of an interrupted line is unknown")
+	assert.Equal(t, Summary{OK: 0, Failed: 3, Skipped: 1}, summary)
+}
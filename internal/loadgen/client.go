@@ -0,0 +1,101 @@
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kaz-as/test-transactions/models"
+)
+
+// client calls the REST API.
+type client struct {
+	base string
+	http *http.Client
+}
+
+// apiError is a response of the API with a non-200 code.
+type apiError struct {
+	code    int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%d %s", e.code, e.message)
+}
+
+func (c *client) createUser(ctx context.Context, balance int64) (string, error) {
+	res := models.CreateUserSuccess{}
+
+	err := c.do(ctx, http.MethodPost, "/user", &models.CreateUser{Balance: &balance}, &res)
+	if err != nil {
+		return "", err
+	}
+
+	if res.ID == nil {
+		return "", errors.New("no id in response")
+	}
+
+	return *res.ID, nil
+}
+
+func (c *client) createTx(ctx context.Context, from, to string, value int64) error {
+	return c.do(ctx, http.MethodPost, "/tx", &models.Tx{From: &from, To: &to, Value: &value}, &models.CreateTxSuccess{})
+}
+
+func (c *client) balance(ctx context.Context, id string) (int64, error) {
+	res := models.UserBalance{}
+
+	err := c.do(ctx, http.MethodGet, "/user/"+id+"/balance", nil, &res)
+	if err != nil {
+		return 0, err
+	}
+
+	if res.Balance == nil {
+		return 0, errors.New("no balance in response")
+	}
+
+	return *res.Balance, nil
+}
+
+func (c *client) do(ctx context.Context, method, path string, body, res interface{}) error {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.base, "/")+path, &buf)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		e := models.Error{}
+		msg := http.StatusText(resp.StatusCode)
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Message != nil {
+			msg = *e.Message
+		}
+		return &apiError{code: resp.StatusCode, message: msg}
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	return nil
+}
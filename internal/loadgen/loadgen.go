@@ -0,0 +1,345 @@
+// Package loadgen measures the API of a running instance with random transfers between its own users.
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+var ErrSupplyChanged = errors.New("total supply changed")
+
+type Options struct {
+	// URL is the base of the REST API, e.g. http://localhost:8080.
+	URL string
+	// Users are created before the load, each with Balance taken from the primary user.
+	Users   int
+	Balance int64
+	// RPS is the target rate of transfers during Duration.
+	RPS      float64
+	Duration time.Duration
+	// Concurrency limits the requests in flight; a transfer due when all of them are busy is dropped.
+	Concurrency int
+	// Zipf is the skew of the users picked for transfers, above 1; the higher, the hotter the first users.
+	Zipf float64
+	// MaxValue limits the random value of a transfer.
+	MaxValue int64
+	Seed     int64
+}
+
+func (o Options) validate() error {
+	switch {
+	case o.Users < 2:
+		return errors.New("at least 2 users required")
+	case o.RPS <= 0:
+		return errors.New("rps must be positive")
+	case o.Duration <= 0:
+		return errors.New("duration must be positive")
+	case o.Zipf <= 1:
+		return errors.New("zipf skew must be above 1")
+	case o.Balance < 0:
+		return errors.New("balance must not be negative")
+	}
+
+	return nil
+}
+
+// Reconciler checks the balances against the transactions in the DB.
+type Reconciler interface {
+	Reconcile(ctx context.Context) ([]domain.Discrepancy, error)
+}
+
+type Generator struct {
+	log        logger.Interface
+	client     *client
+	reconciler Reconciler
+	opts       Options
+}
+
+// New returns a generator; reconciler is optional, without it only the total supply is checked.
+func New(log logger.Interface, reconciler Reconciler, opts Options) *Generator {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if opts.MaxValue < 1 {
+		opts.MaxValue = 1
+	}
+
+	return &Generator{
+		log: log,
+		client: &client{
+			base: opts.URL,
+			http: &http.Client{
+				Timeout:   10 * time.Second,
+				Transport: &http.Transport{MaxIdleConnsPerHost: opts.Concurrency},
+			},
+		},
+		reconciler: reconciler,
+		opts:       opts,
+	}
+}
+
+// Report is the result of a run.
+type Report struct {
+	Users    int
+	Sent     int
+	OK       int
+	Dropped  int
+	Errors   map[string]int
+	Elapsed  time.Duration
+	Latency  Latency
+	Supply   int64
+	Expected int64
+
+	Discrepancies []domain.Discrepancy
+}
+
+// Latency of the transfers, successful or not.
+type Latency struct {
+	P50, P90, P99, P999, Max time.Duration
+}
+
+// Run creates the users, fires the transfers and checks the total supply of the users afterwards.
+// The report is returned even on an error, as far as the run got.
+func (g *Generator) Run(ctx context.Context) (Report, error) {
+	report := Report{Errors: make(map[string]int)}
+
+	if err := g.opts.validate(); err != nil {
+		return report, err
+	}
+
+	users, err := g.createUsers(ctx)
+	report.Users = len(users)
+	if err != nil {
+		return report, err
+	}
+
+	report.Expected = g.opts.Balance * int64(len(users))
+
+	g.log.Info("loadgen: users created", "users", len(users))
+
+	latencies := g.load(ctx, users, &report)
+	report.Latency = percentiles(latencies)
+
+	g.log.Info("loadgen: load done", "sent", report.Sent, "ok", report.OK)
+
+	// the check must not be skipped by the end of the load
+	checkCtx := context.WithoutCancel(ctx)
+
+	report.Supply, err = g.supply(checkCtx, users)
+	if err != nil {
+		return report, fmt.Errorf("supply: %w", err)
+	}
+
+	if g.reconciler != nil {
+		report.Discrepancies, err = g.reconciler.Reconcile(checkCtx)
+		if err != nil {
+			return report, fmt.Errorf("reconcile: %w", err)
+		}
+	}
+
+	if report.Supply != report.Expected || len(report.Discrepancies) > 0 {
+		return report, ErrSupplyChanged
+	}
+
+	return report, nil
+}
+
+func (g *Generator) createUsers(ctx context.Context) ([]string, error) {
+	users := make([]string, g.opts.Users)
+	errs := make(chan error, g.opts.Users)
+	sem := make(chan struct{}, g.opts.Concurrency)
+	wg := sync.WaitGroup{}
+
+	for i := range users {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			id, err := g.client.createUser(ctx, g.opts.Balance)
+			if err != nil {
+				errs <- fmt.Errorf("create user: %w", err)
+				return
+			}
+			users[i] = id
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// load is an open loop: the transfers are due at the target rate, whatever the latency is.
+func (g *Generator) load(ctx context.Context, users []string, report *Report) []time.Duration {
+	ctx, cancel := context.WithTimeout(ctx, g.opts.Duration)
+	defer cancel()
+
+	rnd := rand.New(rand.NewSource(g.opts.Seed))
+	zipf := rand.NewZipf(rnd, g.opts.Zipf, 1, uint64(len(users)-1))
+
+	interval := time.Duration(float64(time.Second) / g.opts.RPS)
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		wg        sync.WaitGroup
+	)
+
+	sem := make(chan struct{}, g.opts.Concurrency)
+	start := time.Now()
+
+	for i := 0; ; i++ {
+		due := start.Add(time.Duration(i) * interval)
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			report.Elapsed = time.Since(start)
+			return latencies
+		case <-time.After(time.Until(due)):
+		}
+
+		from := users[zipf.Uint64()]
+		to := users[zipf.Uint64()]
+		for to == from {
+			to = users[zipf.Uint64()]
+		}
+		value := rnd.Int63n(g.opts.MaxValue) + 1
+
+		select {
+		case sem <- struct{}{}:
+		default:
+			report.Dropped++
+			continue
+		}
+
+		report.Sent++
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			// the requests in flight are finished after the end of the load
+			reqStart := time.Now()
+			err := g.client.createTx(context.WithoutCancel(ctx), from, to, value)
+			latency := time.Since(reqStart)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			latencies = append(latencies, latency)
+			if err != nil {
+				report.Errors[errorKey(err)]++
+				return
+			}
+			report.OK++
+		}()
+	}
+}
+
+func errorKey(err error) string {
+	apiErr := &apiError{}
+	if errors.As(err, &apiErr) {
+		return apiErr.Error()
+	}
+
+	return "transport error"
+}
+
+// supply sums the balances of the users, which only transfer to each other.
+func (g *Generator) supply(ctx context.Context, users []string) (int64, error) {
+	var sum int64
+	for _, id := range users {
+		balance, err := g.client.balance(ctx, id)
+		if err != nil {
+			return 0, fmt.Errorf("user %s: %w", id, err)
+		}
+		sum += balance
+	}
+
+	return sum, nil
+}
+
+func percentiles(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	at := func(p float64) time.Duration {
+		i := int(p*float64(len(latencies))+0.5) - 1
+		if i < 0 {
+			i = 0
+		}
+		return latencies[i]
+	}
+
+	return Latency{
+		P50:  at(0.5),
+		P90:  at(0.9),
+		P99:  at(0.99),
+		P999: at(0.999),
+		Max:  latencies[len(latencies)-1],
+	}
+}
+
+// Print writes the report as a table.
+func (r Report) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	rate := 0.0
+	if r.Elapsed > 0 {
+		rate = float64(r.OK) / r.Elapsed.Seconds()
+	}
+
+	fmt.Fprintf(tw, "users\t%d\n", r.Users)
+	fmt.Fprintf(tw, "elapsed\t%s\n", r.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(tw, "sent\t%d\n", r.Sent)
+	fmt.Fprintf(tw, "ok\t%d\n", r.OK)
+	fmt.Fprintf(tw, "dropped\t%d\n", r.Dropped)
+	fmt.Fprintf(tw, "throughput\t%.1f tx/s\n", rate)
+	fmt.Fprintf(tw, "latency p50\t%s\n", r.Latency.P50)
+	fmt.Fprintf(tw, "latency p90\t%s\n", r.Latency.P90)
+	fmt.Fprintf(tw, "latency p99\t%s\n", r.Latency.P99)
+	fmt.Fprintf(tw, "latency p99.9\t%s\n", r.Latency.P999)
+	fmt.Fprintf(tw, "latency max\t%s\n", r.Latency.Max)
+
+	keys := make([]string, 0, len(r.Errors))
+	for k := range r.Errors {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(tw, "error %s\t%d\n", k, r.Errors[k])
+	}
+
+	fmt.Fprintf(tw, "supply\t%d of %d\n", r.Supply, r.Expected)
+	for _, d := range r.Discrepancies {
+		fmt.Fprintf(tw, "discrepancy %s\tbalance %d, transactions %d\n", d.UserID, d.Balance, d.Expected)
+	}
+
+	return tw.Flush()
+}
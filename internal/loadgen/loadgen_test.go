@@ -0,0 +1,122 @@
+package loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kaz-as/test-transactions/models"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+// fakeAPI keeps the balances in memory; leak is added to every transfer to break the supply.
+type fakeAPI struct {
+	mu       sync.Mutex
+	balances map[string]int64
+	leak     int64
+}
+
+func (f *fakeAPI) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /user", func(w http.ResponseWriter, r *http.Request) {
+		req := models.CreateUser{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		f.mu.Lock()
+		id := fmt.Sprintf("%032x", len(f.balances)+1)
+		f.balances[id] = *req.Balance
+		f.mu.Unlock()
+
+		_ = json.NewEncoder(w).Encode(models.CreateUserSuccess{ID: &id})
+	})
+
+	mux.HandleFunc("POST /tx", func(w http.ResponseWriter, r *http.Request) {
+		req := models.Tx{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if f.balances[*req.From] < *req.Value {
+			msg := "insufficient balance"
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_ = json.NewEncoder(w).Encode(models.Error{Message: &msg})
+			return
+		}
+
+		f.balances[*req.From] -= *req.Value
+		f.balances[*req.To] += *req.Value + f.leak
+
+		_ = json.NewEncoder(w).Encode(models.CreateTxSuccess{})
+	})
+
+	mux.HandleFunc("GET /user/{id}/balance", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		balance := f.balances[r.PathValue("id")]
+		f.mu.Unlock()
+
+		_ = json.NewEncoder(w).Encode(models.UserBalance{Balance: &balance})
+	})
+
+	return mux
+}
+
+func TestRun(t *testing.T) {
+	for _, leak := range []int64{0, 1} {
+		api := &fakeAPI{balances: make(map[string]int64), leak: leak}
+		srv := httptest.NewServer(api.handler())
+
+		report, err := New(logger.Nop(), nil, Options{
+			URL:         srv.URL,
+			Users:       10,
+			Balance:     5,
+			RPS:         500,
+			Duration:    200 * time.Millisecond,
+			Concurrency: 8,
+			Zipf:        1.5,
+			MaxValue:    3,
+			Seed:        1,
+		}).Run(context.Background())
+
+		srv.Close()
+
+		assert.Equal(t, 10, report.Users)
+		assert.Equal(t, int64(50), report.Expected)
+		assert.NotZero(t, report.Sent)
+		assert.Equal(t, report.Sent, report.OK+report.Errors["422 insufficient balance"])
+		assert.NotZero(t, report.Latency.Max)
+
+		if leak == 0 {
+			require.NoError(t, err)
+			assert.Equal(t, int64(50), report.Supply)
+		} else {
+			assert.ErrorIs(t, err, ErrSupplyChanged)
+		}
+	}
+}
+
+func TestPercentiles(t *testing.T) {
+	latencies := make([]time.Duration, 1000)
+	for i := range latencies {
+		latencies[len(latencies)-1-i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	assert.Equal(t, Latency{
+		P50:  500 * time.Millisecond,
+		P90:  900 * time.Millisecond,
+		P99:  990 * time.Millisecond,
+		P999: 999 * time.Millisecond,
+		Max:  1000 * time.Millisecond,
+	}, percentiles(latencies))
+
+	assert.Equal(t, Latency{}, percentiles(nil))
+}
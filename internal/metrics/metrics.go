@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/kaz-as/test-transactions/domain"
+)
+
+const namespace = "tx"
+
+// Transfer outcomes.
+const (
+	OutcomeSuccess             = "success"
+	OutcomeInsufficientBalance = "insufficient_balance"
+	OutcomeSameAccount         = "same_account"
+	OutcomeNegativeValue       = "negative_value"
+	OutcomeTooMuch             = "too_much"
+	OutcomeInvalidDetails      = "invalid_details"
+	OutcomeDuplicateReference  = "duplicate_reference"
+	OutcomeDenied              = "denied"
+	OutcomeReviewRequired      = "review_required"
+	OutcomePreconditionFailed  = "precondition_failed"
+	OutcomeLockTimeout         = "lock_timeout"
+	OutcomeStatementTimeout    = "statement_timeout"
+	OutcomeQueueFull           = "queue_full"
+	OutcomeError               = "error"
+)
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+
+	transfers      *prometheus.CounterVec
+	transferVolume *prometheus.CounterVec
+
+	dbTxDuration  *prometheus.HistogramVec
+	dbTxRollbacks *prometheus.CounterVec
+	dbTxAttempts  *prometheus.HistogramVec
+}
+
+// New registers all the app metrics along with Go runtime, process and db pool stats collectors.
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by swagger operation and response code.",
+		}, []string{"operation", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by swagger operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+
+		transfers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transfers_total",
+			Help:      "Transfers by use case operation and outcome.",
+		}, []string{"operation", "outcome"}),
+		transferVolume: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transfer_volume_total",
+			Help:      "Sum of transfer values by use case operation and outcome.",
+		}, []string{"operation", "outcome"}),
+
+		dbTxDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_transaction_duration_seconds",
+			Help:      "DB transaction duration from begin to commit or rollback.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation", "result"}),
+		dbTxRollbacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_transaction_rollbacks_total",
+			Help:      "DB transactions that were not committed.",
+		}, []string{"operation"}),
+		dbTxAttempts: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_transaction_attempts",
+			Help:      "Attempts made by a use case operation to run its DB transaction, retries included.",
+			Buckets:   []float64{1, 2, 3, 5, 8},
+		}, []string{"operation", "result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, namespace),
+		m.requests,
+		m.requestDuration,
+		m.transfers,
+		m.transferVolume,
+		m.dbTxDuration,
+		m.dbTxRollbacks,
+		m.dbTxAttempts,
+	)
+
+	return m
+}
+
+// Handler exposes the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+func (m *Metrics) ObserveRequest(operation string, code int, duration time.Duration) {
+	m.requests.WithLabelValues(operation, strconv.Itoa(code)).Inc()
+	m.requestDuration.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+func (m *Metrics) ObserveTransfer(operation, outcome string, value domain.Balance) {
+	m.transfers.WithLabelValues(operation, outcome).Inc()
+	m.transferVolume.WithLabelValues(operation, outcome).Add(float64(value))
+}
+
+func (m *Metrics) ObserveDBTx(operation string, duration time.Duration, committed bool) {
+	result := "commit"
+	if !committed {
+		result = "rollback"
+		m.dbTxRollbacks.WithLabelValues(operation).Inc()
+	}
+
+	m.dbTxDuration.WithLabelValues(operation, result).Observe(duration.Seconds())
+}
+
+func (m *Metrics) ObserveDBTxAttempts(operation string, attempts int, committed bool) {
+	result := "commit"
+	if !committed {
+		result = "rollback"
+	}
+
+	m.dbTxAttempts.WithLabelValues(operation, result).Observe(float64(attempts))
+}
@@ -1,10 +1,23 @@
 package middlewares
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
+	"time"
+
+	"github.com/go-openapi/runtime/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/kaz-as/test-transactions/pkg/logger"
+	"github.com/kaz-as/test-transactions/pkg/tracing"
 )
 
 type Middleware func(http.Handler) http.Handler
@@ -22,13 +35,29 @@ func Chain(mws []Middleware) Middleware {
 	}
 }
 
+// statusWriter remembers the response code.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(statusCode int) {
+	w.status = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush a stream.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 //// Global
 
 func Logger(l logger.Interface) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if r != nil {
-				l.Info("new request: %v \"%v\"", r.Method, r.RequestURI)
+				l.Ctx(r.Context()).Info("new request", "method", r.Method, "uri", r.RequestURI)
 			} else {
 				l.Warn("logger: request is nil")
 			}
@@ -42,7 +71,11 @@ func Recoverer(l logger.Interface) Middleware {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if rec := recover(); rec != nil {
-					l.Error("panic: %v", rec)
+					log := l
+					if r != nil {
+						log = l.Ctx(r.Context())
+					}
+					log.Error("panic", "panic", rec)
 					jsonBody, _ := json.Marshal(map[string]string{
 						"error": "There was an internal server error",
 					})
@@ -53,7 +86,7 @@ func Recoverer(l logger.Interface) Middleware {
 					w.WriteHeader(http.StatusInternalServerError)
 					_, err := w.Write(jsonBody)
 					if err != nil {
-						l.Error("cannot write internal server error: %s", err)
+						log.Error("cannot write internal server error", "error", err)
 					}
 				}
 			}()
@@ -62,4 +95,172 @@ func Recoverer(l logger.Interface) Middleware {
 	}
 }
 
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+// RequestIDFrom returns the id stored in the context by the RequestID middleware.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestID propagates X-Request-ID of the request or generates a new one. The id is sent back in the response header
+// and stored in the request context, also as the request_id log field.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			id := ResolveRequestID(r.Header.Get(RequestIDHeader))
+
+			if w.Header() != nil {
+				w.Header().Set(RequestIDHeader, id)
+			}
+
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			ctx = logger.ContextWith(ctx, "request_id", id)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ResolveRequestID returns the id got from a client if it is valid, else a new one.
+func ResolveRequestID(id string) string {
+	if !validRequestID(id) {
+		return newRequestID()
+	}
+
+	return id
+}
+
+// validRequestID accepts only short printable ASCII ids, so a client cannot break the logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// Tracing starts a server span for every request, continuing the trace from the W3C trace context headers.
+func Tracing() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracing.Start(ctx, "HTTP "+r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.URLPath(r.URL.Path),
+					attribute.String("request.id", RequestIDFrom(r.Context())),
+				),
+			)
+			defer span.End()
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(sw.status))
+			if sw.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(sw.status))
+			}
+		})
+	}
+}
+
 //// Local
+
+// SpanName names the request span after the swagger operation id.
+func SpanName() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route := middleware.MatchedRouteFrom(r); route != nil && route.Operation != nil {
+				trace.SpanFromContext(r.Context()).SetName(route.Operation.ID)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SwaggerRoute names the route of the request for Metrics after the swagger operation id,
+// so it must be applied by the API router after the route is matched.
+func SwaggerRoute() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route := middleware.MatchedRouteFrom(r); route != nil && route.Operation != nil {
+				setRoute(r.Context(), route.Operation.ID)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Route names the route of the request for Metrics, for a handler out of the swagger API.
+func Route(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			setRoute(r.Context(), name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type RequestObserver interface {
+	ObserveRequest(operation string, code int, duration time.Duration)
+}
+
+const unknownRoute = "unknown"
+
+type routeKey struct{}
+
+// setRoute names the route in the slot put in the context by Metrics, if any.
+func setRoute(ctx context.Context, name string) {
+	if route, ok := ctx.Value(routeKey{}).(*string); ok {
+		*route = name
+	}
+}
+
+// Metrics reports every request by its route, "unknown" if none is matched, e.g. for a 404. It is global,
+// so the requests failed before the routing are counted too; the route is named by SwaggerRoute or Route.
+func Metrics(o RequestObserver) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+			route := unknownRoute
+			if r != nil {
+				r = r.WithContext(context.WithValue(r.Context(), routeKey{}, &route))
+			}
+
+			next.ServeHTTP(sw, r)
+
+			o.ObserveRequest(route, sw.status, time.Since(start))
+		})
+	}
+}
@@ -1,13 +1,20 @@
 package middlewares
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kaz-as/test-transactions/pkg/logger"
 )
 
 type writer struct {
@@ -83,17 +90,19 @@ type lg struct {
 	messages chan string
 }
 
-func (l *lg) Debug(message interface{}, args ...interface{}) { l.msg(message, args...) }
-func (l *lg) Info(message string, args ...interface{})       { l.msg(message, args...) }
-func (l *lg) Warn(message string, args ...interface{})       { l.msg(message, args...) }
-func (l *lg) Error(message interface{}, args ...interface{}) { l.msg(message, args...) }
-func (l *lg) Fatal(message interface{}, args ...interface{}) { l.msg(message, args...) }
+func (l *lg) Debug(msg string, keyvals ...interface{}) { l.msg(msg, keyvals...) }
+func (l *lg) Info(msg string, keyvals ...interface{})  { l.msg(msg, keyvals...) }
+func (l *lg) Warn(msg string, keyvals ...interface{})  { l.msg(msg, keyvals...) }
+func (l *lg) Error(msg string, keyvals ...interface{}) { l.msg(msg, keyvals...) }
+func (l *lg) Fatal(msg string, keyvals ...interface{}) { l.msg(msg, keyvals...) }
+
+func (l *lg) With(...interface{}) logger.Interface { return l }
+func (l *lg) Ctx(context.Context) logger.Interface { return l }
 
-func (l *lg) msg(message interface{}, args ...interface{}) {
-	msgStr, ok := message.(string)
-	assert.Equalf(l.t, true, ok, "message should be string")
+func (l *lg) msg(msg string, keyvals ...interface{}) {
+	assert.Equalf(l.t, 0, len(keyvals)%2, "key/value pairs expected")
 	go func() {
-		l.messages <- fmt.Sprintf(msgStr, args...)
+		l.messages <- fmt.Sprint(append([]interface{}{msg}, keyvals...)...)
 	}()
 }
 
@@ -180,3 +189,96 @@ func TestRecoverer(t *testing.T) {
 
 	assert.Empty(t, wr.sb.String(), "no error should be sent to the response if not panics")
 }
+
+type requestObserver struct {
+	operation string
+	code      int
+	calls     int
+}
+
+func (o *requestObserver) ObserveRequest(operation string, code int, _ time.Duration) {
+	o.operation = operation
+	o.code = code
+	o.calls++
+}
+
+func TestMetrics(t *testing.T) {
+	o := &requestObserver{}
+	r := &http.Request{Method: http.MethodGet, RequestURI: "localhost"}
+
+	Metrics(o)(handler{statusCode: 404}).ServeHTTP(&writer{}, r)
+
+	assert.Equal(t, 1, o.calls)
+	assert.Equal(t, "unknown", o.operation, "no route is matched")
+	assert.Equal(t, 404, o.code)
+
+	Metrics(o)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})).ServeHTTP(&writer{}, r)
+
+	assert.Equal(t, 2, o.calls)
+	assert.Equal(t, 200, o.code, "implicit status must be 200")
+
+	Metrics(o)(Chain([]Middleware{Logger(logger.Nop()), Route("balanceEvents")})(_successHandler)).
+		ServeHTTP(&writer{}, r)
+
+	assert.Equal(t, 3, o.calls)
+	assert.Equal(t, "balanceEvents", o.operation, "the route is named by an inner middleware")
+}
+
+func TestTracing(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	r, err := http.NewRequest(http.MethodGet, "http://localhost/user", nil)
+	assert.NoError(t, err)
+	r.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	var got string
+	Tracing()(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = trace.SpanContextFromContext(r.Context()).TraceID().String()
+	})).ServeHTTP(&writer{}, r)
+
+	assert.Equal(t, traceID, got, "trace must be continued from the incoming headers")
+
+	assert.NotPanics(t, func() {
+		Tracing()(_successHandler).ServeHTTP(&writer{}, nil)
+	}, "must not panic on nil request")
+}
+
+func TestRequestID(t *testing.T) {
+	serve := func(header string) (inCtx string, inResponse string) {
+		r, err := http.NewRequest(http.MethodGet, "http://localhost/user", nil)
+		assert.NoError(t, err)
+		if header != "" {
+			r.Header.Set(RequestIDHeader, header)
+		}
+
+		w := httptest.NewRecorder()
+		RequestID()(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			inCtx = RequestIDFrom(r.Context())
+		})).ServeHTTP(w, r)
+
+		return inCtx, w.Header().Get(RequestIDHeader)
+	}
+
+	inCtx, inResponse := serve("abc-123")
+	assert.Equal(t, "abc-123", inCtx, "valid id must be propagated")
+	assert.Equal(t, "abc-123", inResponse)
+
+	inCtx, inResponse = serve("")
+	assert.Len(t, inCtx, 32, "missing id must be generated")
+	assert.Equal(t, inCtx, inResponse)
+
+	inCtx, _ = serve("bad\tid")
+	assert.NotEqual(t, "bad\tid", inCtx, "invalid id must be replaced")
+	assert.Len(t, inCtx, 32)
+
+	inCtx, _ = serve(strings.Repeat("a", 129))
+	assert.Len(t, inCtx, 32, "too long id must be replaced")
+
+	assert.NotPanics(t, func() {
+		RequestID()(_successHandler).ServeHTTP(&writer{}, nil)
+	}, "must not panic on nil request")
+}
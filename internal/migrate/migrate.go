@@ -0,0 +1,128 @@
+// Package migrate applies the migrations embedded into the binary.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+
+	"github.com/kaz-as/test-transactions/config"
+	"github.com/kaz-as/test-transactions/migrations"
+)
+
+// Commands.
+const (
+	Up      = "up"
+	Down    = "down"
+	Status  = "status"
+	Version = "version"
+)
+
+var ErrUnknownCommand = errors.New("unknown command")
+
+// lockID is a key of the session advisory lock that serializes migrations between app instances.
+const lockID = 20230114222907
+
+// NewProvider returns a goose provider of the embedded migrations of the driver. On Postgres it holds
+// an advisory lock while migrating, so several app instances can run it at once.
+func NewProvider(db *sql.DB, driver string) (*goose.Provider, error) {
+	if driver == config.DriverSQLite {
+		p, err := goose.NewProvider(goose.DialectSQLite3, db, migrations.SQLiteFS)
+		if err != nil {
+			return nil, fmt.Errorf("goose provider: %w", err)
+		}
+
+		return p, nil
+	}
+
+	locker, err := lock.NewPostgresSessionLocker(lock.WithLockID(lockID))
+	if err != nil {
+		return nil, fmt.Errorf("session locker: %w", err)
+	}
+
+	p, err := goose.NewProvider(goose.DialectPostgres, db, migrations.FS, goose.WithSessionLocker(locker))
+	if err != nil {
+		return nil, fmt.Errorf("goose provider: %w", err)
+	}
+
+	return p, nil
+}
+
+// ExpectedVersion returns the latest migration of the driver the app is built for.
+func ExpectedVersion(driver string) int64 {
+	if driver == config.DriverSQLite {
+		return migrations.SQLiteVersion
+	}
+
+	return migrations.Version
+}
+
+// Run runs the command and writes its result to out.
+func Run(ctx context.Context, db *sql.DB, driver string, out io.Writer, command string) error {
+	p, err := NewProvider(db, driver)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case Up:
+		results, err := p.Up(ctx)
+		for _, r := range results {
+			_, _ = fmt.Fprintln(out, r)
+		}
+		if err != nil {
+			return fmt.Errorf("up: %w", err)
+		}
+		if len(results) == 0 {
+			_, _ = fmt.Fprintln(out, "no migrations to apply")
+		}
+
+	case Down:
+		result, err := p.Down(ctx)
+		if result != nil {
+			_, _ = fmt.Fprintln(out, result)
+		}
+		if err != nil {
+			return fmt.Errorf("down: %w", err)
+		}
+
+	case Status:
+		statuses, err := p.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("status: %w", err)
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "Applied At\tMigration")
+		for _, s := range statuses {
+			appliedAt := "Pending"
+			if s.State == goose.StateApplied {
+				appliedAt = s.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\n", appliedAt, filepath.Base(s.Source.Path))
+		}
+		if err = w.Flush(); err != nil {
+			return fmt.Errorf("status: %w", err)
+		}
+
+	case Version:
+		current, err := p.GetDBVersion(ctx)
+		if err != nil {
+			return fmt.Errorf("version: %w", err)
+		}
+		_, _ = fmt.Fprintf(out, "version %d, the app is built for %d\n", current, ExpectedVersion(driver))
+
+	default:
+		return fmt.Errorf("%q: %w", command, ErrUnknownCommand)
+	}
+
+	return nil
+}
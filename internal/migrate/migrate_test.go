@@ -0,0 +1,53 @@
+package migrate
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kaz-as/test-transactions/config"
+	"github.com/kaz-as/test-transactions/internal/sqlite"
+)
+
+func TestPostingsBackfill(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "tx.db"), time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	p, err := NewProvider(db, config.DriverSQLite)
+	require.NoError(t, err)
+	_, err = p.UpTo(ctx, 20261019170000)
+	require.NoError(t, err)
+
+	const primary = "00000000000000000000000000000000"
+	_, err = db.ExecContext(ctx, `
+INSERT INTO users (id, balance) VALUES ('a', 90);
+UPDATE primary_shards SET balance = balance - 90 WHERE id = 0;
+INSERT INTO transactions (id, "from", "to", value, timestamp) VALUES
+    ('tx1', ?1, 'a', 100, 1),
+    ('tx2', 'a', ?1, 10, 2)`, primary)
+	require.NoError(t, err)
+
+	_, err = p.Up(ctx)
+	require.NoError(t, err)
+
+	var seqs, balances [4]int64
+	err = db.QueryRowContext(ctx, `
+SELECT t1.from_seq, t1.from_balance, t2.to_seq, t2.to_balance, t1.to_seq, t1.to_balance, t2.from_seq, t2.from_balance
+FROM transactions t1, transactions t2
+WHERE t1.id = 'tx1' AND t2.id = 'tx2'`,
+	).Scan(&seqs[0], &balances[0], &seqs[1], &balances[1], &seqs[2], &balances[2], &seqs[3], &balances[3])
+	require.NoError(t, err)
+	assert.Equal(t, [4]int64{1, 2, 1, 2}, seqs)
+	assert.Equal(t, [4]int64{1000000000 - 100, 1000000000 - 90, 100, 90}, balances,
+		"the balances of the primary user are counted back from the sum of its shards")
+
+	var seq int64
+	require.NoError(t, db.QueryRowContext(ctx, `SELECT seq FROM users WHERE id = ?1`, primary).Scan(&seq))
+	assert.Equal(t, int64(2), seq)
+}
@@ -0,0 +1,120 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kaz-as/test-transactions/domain"
+)
+
+// Config lists the rules in the order they are checked; the default rules if empty.
+type Config struct {
+	Rules []RuleConfig
+}
+
+// RuleConfig is a rule by Name with its settings: Decision (deny or review) of the rules that can send
+// a transfer to review, Limit of max_value and Users of blocked_users.
+type RuleConfig struct {
+	Name     string
+	Decision string
+	Limit    int64
+	Users    []string
+}
+
+// Factory builds a rule from its config.
+type Factory func(cfg RuleConfig) (Rule, error)
+
+var factories = map[string]Factory{
+	RuleSameAccount:         fixed(SameAccount()),
+	RuleNegativeValue:       fixed(NegativeValue()),
+	RuleInsufficientBalance: fixed(InsufficientBalance()),
+	RuleOverflow:            fixed(Overflow()),
+	RuleMaxValue: func(cfg RuleConfig) (Rule, error) {
+		d, err := decision(cfg.Decision, Review)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Limit < 0 {
+			return nil, fmt.Errorf("negative limit %d", cfg.Limit)
+		}
+		return MaxValue(domain.Balance(cfg.Limit), d), nil
+	},
+	RuleBlockedUsers: func(cfg RuleConfig) (Rule, error) {
+		d, err := decision(cfg.Decision, Deny)
+		if err != nil {
+			return nil, err
+		}
+		users := make([]domain.UserID, 0, len(cfg.Users))
+		for _, id := range cfg.Users {
+			users = append(users, domain.UserID(id))
+		}
+		return BlockedUsers(users, d), nil
+	},
+}
+
+// required keep the balances consistent, so the config cannot drop them: e.g. a transfer to the sender itself
+// would credit it on top of the debit, and a negative one would take money from the receiver.
+var required = []string{RuleSameAccount, RuleNegativeValue, RuleInsufficientBalance, RuleOverflow}
+
+// Register makes a rule available to the config by the name. It must be called before FromConfig, e.g. in init.
+func Register(name string, f Factory) {
+	factories[name] = f
+}
+
+// FromConfig returns the engine of the configured rules, the default one if there are none.
+// The config can reorder the default rules and add others, but not drop them. As the first deny wins,
+// the order decides which rule a transfer breaking several of them is rejected by.
+func FromConfig(cfg Config) (*Engine, error) {
+	if len(cfg.Rules) == 0 {
+		return Default(), nil
+	}
+
+	rules := make([]Rule, 0, len(cfg.Rules))
+	names := make(map[string]bool, len(cfg.Rules))
+	for i, rc := range cfg.Rules {
+		f, ok := factories[rc.Name]
+		if !ok {
+			return nil, fmt.Errorf("policy rule %d: unknown rule %q", i, rc.Name)
+		}
+
+		rule, err := f(rc)
+		if err != nil {
+			return nil, fmt.Errorf("policy rule %s: %w", rc.Name, err)
+		}
+
+		rules = append(rules, rule)
+		names[rc.Name] = true
+	}
+
+	for _, name := range required {
+		if !names[name] {
+			return nil, fmt.Errorf("policy rule %s is required", name)
+		}
+	}
+
+	return New(rules...), nil
+}
+
+// fixed is the factory of a rule that always denies.
+func fixed(rule Rule) Factory {
+	return func(cfg RuleConfig) (Rule, error) {
+		if _, err := decision(cfg.Decision, Deny); err != nil {
+			return nil, err
+		}
+		if cfg.Decision == string(Review) {
+			return nil, errors.New("the rule always denies")
+		}
+		return rule, nil
+	}
+}
+
+func decision(s string, def Decision) (Decision, error) {
+	switch Decision(s) {
+	case "":
+		return def, nil
+	case Deny, Review:
+		return Decision(s), nil
+	default:
+		return "", fmt.Errorf("unknown decision %q, must be deny or review", s)
+	}
+}
@@ -0,0 +1,107 @@
+// Package policy decides whether a transfer may proceed. The rules are checked in order against the transaction
+// and its accounts locked by the use case, so they see the balances the transfer is applied to.
+package policy
+
+import (
+	"context"
+
+	"github.com/kaz-as/test-transactions/domain"
+)
+
+type Decision string
+
+const (
+	Allow Decision = "allow"
+	Deny  Decision = "deny"
+	// Review stops the transfer until an operator approves it.
+	Review Decision = "review"
+)
+
+// Verdict is the decision of a rule with its reason code; Message details the reason for the client.
+type Verdict struct {
+	Decision Decision
+	// Rule is the name of the rule that made the decision, empty if every rule allowed the transfer.
+	Rule    string
+	Reason  string
+	Message string
+}
+
+type Rule interface {
+	// Name identifies the rule in the config and in the verdicts.
+	Name() string
+	Check(ctx context.Context, tx *domain.Tx, from, to *domain.User) Verdict
+}
+
+type Engine struct {
+	rules []Rule
+}
+
+// New returns the engine checking the rules in the given order.
+func New(rules ...Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// Default returns the engine of the built-in checks every ledger needs.
+func Default() *Engine {
+	return New(SameAccount(), NegativeValue(), InsufficientBalance(), Overflow())
+}
+
+// Evaluate returns the first deny. Otherwise it is the first review, as a later rule can still deny the transfer.
+func (e *Engine) Evaluate(ctx context.Context, tx *domain.Tx, from, to *domain.User) Verdict {
+	verdict := Verdict{Decision: Allow}
+
+	for _, rule := range e.rules {
+		v := rule.Check(ctx, tx, from, to)
+		v.Rule = rule.Name()
+
+		switch v.Decision {
+		case Deny:
+			return v
+		case Review:
+			if verdict.Decision == Allow {
+				verdict = v
+			}
+		}
+	}
+
+	return verdict
+}
+
+// Violations returns the deny and review verdicts of all the rules, in their order; none if the transfer is allowed.
+// Unlike Evaluate, it checks the rules after a deny too.
+func (e *Engine) Violations(ctx context.Context, tx *domain.Tx, from, to *domain.User) []Verdict {
+	var verdicts []Verdict
+
+	for _, rule := range e.rules {
+		v := rule.Check(ctx, tx, from, to)
+		v.Rule = rule.Name()
+
+		if v.Decision != Allow {
+			verdicts = append(verdicts, v)
+		}
+	}
+
+	return verdicts
+}
+
+// WithoutBalances returns the engine of the rules that check the transaction and the ids of its users only,
+// so it can be evaluated before the users are locked. ok is false unless all the other rules are the checks
+// of the balances, insufficient_balance and overflow, which the DB can enforce in the statement of the transfer.
+func (e *Engine) WithoutBalances() (_ *Engine, ok bool) {
+	var rules []Rule
+	for _, rule := range e.rules {
+		switch rule.(type) {
+		case sameAccount, negativeValue, maxValue, blockedUsers:
+			rules = append(rules, rule)
+		case insufficientBalance, overflow:
+		default:
+			return nil, false
+		}
+	}
+
+	return New(rules...), true
+}
+
+func allow() Verdict {
+	return Verdict{Decision: Allow}
+}
@@ -0,0 +1,140 @@
+package policy
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kaz-as/test-transactions/domain"
+)
+
+func TestDefault(t *testing.T) {
+	a := &domain.User{ID: "a", Balance: 10}
+	b := &domain.User{ID: "b", Balance: math.MaxInt64 - 5}
+
+	tests := []struct {
+		tx       domain.Tx
+		from, to *domain.User
+		rule     string
+		reason   string
+	}{
+		{domain.Tx{Value: 1}, a, a, RuleSameAccount, ReasonSameAccount},
+		{domain.Tx{Value: -1}, a, b, RuleNegativeValue, ReasonNegativeValue},
+		{domain.Tx{Value: 11}, a, b, RuleInsufficientBalance, ReasonInsufficientBalance},
+		{domain.Tx{Value: 6}, a, b, RuleOverflow, ReasonTooMuch},
+	}
+
+	for _, tt := range tests {
+		v := Default().Evaluate(context.Background(), &tt.tx, tt.from, tt.to)
+		assert.Equal(t, Deny, v.Decision, tt.rule)
+		assert.Equal(t, tt.rule, v.Rule)
+		assert.Equal(t, tt.reason, v.Reason)
+	}
+
+	v := Default().Evaluate(context.Background(), &domain.Tx{Value: 5}, a, b)
+	assert.Equal(t, Verdict{Decision: Allow}, v)
+}
+
+func TestEvaluateOrder(t *testing.T) {
+	a := &domain.User{ID: "a", Balance: 100}
+	b := &domain.User{ID: "b"}
+
+	e := New(MaxValue(10, Review), BlockedUsers([]domain.UserID{"b"}, Deny))
+	v := e.Evaluate(context.Background(), &domain.Tx{Value: 20}, a, b)
+	assert.Equal(t, Deny, v.Decision, "a later deny overrides a review")
+	assert.Equal(t, RuleBlockedUsers, v.Rule)
+
+	e = New(MaxValue(10, Review), MaxValue(15, Review))
+	v = e.Evaluate(context.Background(), &domain.Tx{Value: 20}, a, b)
+	assert.Equal(t, Review, v.Decision)
+	assert.Equal(t, "value=20 is over 10", v.Message, "the first review is returned")
+}
+
+func TestViolations(t *testing.T) {
+	a := &domain.User{ID: "a", Balance: 100}
+	b := &domain.User{ID: "b"}
+
+	e := New(BlockedUsers([]domain.UserID{"b"}, Deny), MaxValue(10, Review), InsufficientBalance(), Overflow())
+	vs := e.Violations(context.Background(), &domain.Tx{Value: 200}, a, b)
+	require.Len(t, vs, 3, "the rules after a deny are checked too")
+	assert.Equal(t, []string{RuleBlockedUsers, RuleMaxValue, RuleInsufficientBalance},
+		[]string{vs[0].Rule, vs[1].Rule, vs[2].Rule})
+	assert.Equal(t, []Decision{Deny, Review, Deny}, []Decision{vs[0].Decision, vs[1].Decision, vs[2].Decision})
+
+	assert.Empty(t, e.Violations(context.Background(), &domain.Tx{Value: 5}, a, &domain.User{ID: "c"}))
+}
+
+type balanceRule struct{}
+
+func (balanceRule) Name() string {
+	return "balance_rule"
+}
+
+func (balanceRule) Check(context.Context, *domain.Tx, *domain.User, *domain.User) Verdict {
+	return allow()
+}
+
+func TestWithoutBalances(t *testing.T) {
+	e, ok := New(SameAccount(), InsufficientBalance(), MaxValue(10, Review), Overflow()).WithoutBalances()
+	require.True(t, ok)
+	assert.Equal(t, []Rule{SameAccount(), MaxValue(10, Review)}, e.rules)
+
+	_, ok = New(SameAccount(), balanceRule{}).WithoutBalances()
+	assert.False(t, ok, "a custom rule may read the balances")
+}
+
+func TestFromConfig(t *testing.T) {
+	e, err := FromConfig(Config{})
+	require.NoError(t, err)
+	assert.Len(t, e.rules, 4)
+
+	defaults := []RuleConfig{
+		{Name: RuleSameAccount},
+		{Name: RuleNegativeValue},
+		{Name: RuleInsufficientBalance},
+		{Name: RuleOverflow},
+	}
+
+	e, err = FromConfig(Config{Rules: append(defaults, RuleConfig{Name: RuleMaxValue, Limit: 1000})})
+	require.NoError(t, err)
+	v := e.Evaluate(context.Background(), &domain.Tx{Value: 1001},
+		&domain.User{ID: "a", Balance: 2000}, &domain.User{ID: "b"})
+	assert.Equal(t, Review, v.Decision, "max_value sends to review by default")
+
+	for _, rules := range [][]RuleConfig{
+		append(defaults[:4:4], RuleConfig{Name: "unknown"}),
+		defaults[1:],
+		append(defaults[:3:3], RuleConfig{Name: RuleOverflow, Decision: "review"}),
+		append(defaults[:4:4], RuleConfig{Name: RuleBlockedUsers, Decision: "allow"}),
+	} {
+		_, err = FromConfig(Config{Rules: rules})
+		assert.Error(t, err, rules)
+	}
+}
+
+func TestFromConfigOrder(t *testing.T) {
+	a := &domain.User{ID: "a", Balance: 10}
+	tx := &domain.Tx{Value: 11}
+
+	e, err := FromConfig(Config{Rules: []RuleConfig{
+		{Name: RuleSameAccount},
+		{Name: RuleNegativeValue},
+		{Name: RuleInsufficientBalance},
+		{Name: RuleOverflow},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, RuleSameAccount, e.Evaluate(context.Background(), tx, a, a).Rule)
+
+	e, err = FromConfig(Config{Rules: []RuleConfig{
+		{Name: RuleInsufficientBalance},
+		{Name: RuleSameAccount},
+		{Name: RuleNegativeValue},
+		{Name: RuleOverflow},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, RuleInsufficientBalance, e.Evaluate(context.Background(), tx, a, a).Rule,
+		"the first rule denying the transfer is reported")
+}
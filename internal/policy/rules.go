@@ -0,0 +1,161 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/kaz-as/test-transactions/domain"
+)
+
+// Reason codes of the built-in rules; the ones of the default rules match the transfer outcomes of the metrics.
+const (
+	ReasonSameAccount         = "same_account"
+	ReasonNegativeValue       = "negative_value"
+	ReasonInsufficientBalance = "insufficient_balance"
+	ReasonTooMuch             = "too_much"
+	ReasonValueOverLimit      = "value_over_limit"
+	ReasonBlockedUser         = "blocked_user"
+)
+
+// Names of the built-in rules.
+const (
+	RuleSameAccount         = "same_account"
+	RuleNegativeValue       = "negative_value"
+	RuleInsufficientBalance = "insufficient_balance"
+	RuleOverflow            = "overflow"
+	RuleMaxValue            = "max_value"
+	RuleBlockedUsers        = "blocked_users"
+)
+
+type sameAccount struct{}
+
+// SameAccount denies a transfer to the sender itself.
+func SameAccount() Rule {
+	return sameAccount{}
+}
+
+func (sameAccount) Name() string {
+	return RuleSameAccount
+}
+
+func (sameAccount) Check(_ context.Context, _ *domain.Tx, from, to *domain.User) Verdict {
+	if from.ID == to.ID {
+		return Verdict{Decision: Deny, Reason: ReasonSameAccount, Message: fmt.Sprintf("user id=%s", from.ID)}
+	}
+
+	return allow()
+}
+
+type negativeValue struct{}
+
+func NegativeValue() Rule {
+	return negativeValue{}
+}
+
+func (negativeValue) Name() string {
+	return RuleNegativeValue
+}
+
+func (negativeValue) Check(_ context.Context, tx *domain.Tx, _, _ *domain.User) Verdict {
+	if tx.Value < 0 {
+		return Verdict{Decision: Deny, Reason: ReasonNegativeValue, Message: fmt.Sprintf("value=%d", tx.Value)}
+	}
+
+	return allow()
+}
+
+type insufficientBalance struct{}
+
+func InsufficientBalance() Rule {
+	return insufficientBalance{}
+}
+
+func (insufficientBalance) Name() string {
+	return RuleInsufficientBalance
+}
+
+func (insufficientBalance) Check(_ context.Context, tx *domain.Tx, from, _ *domain.User) Verdict {
+	if from.Balance < tx.Value {
+		return Verdict{Decision: Deny, Reason: ReasonInsufficientBalance, Message: fmt.Sprintf("user id=%s", from.ID)}
+	}
+
+	return allow()
+}
+
+type overflow struct{}
+
+// Overflow denies a transfer the balance of the receiver cannot hold.
+func Overflow() Rule {
+	return overflow{}
+}
+
+func (overflow) Name() string {
+	return RuleOverflow
+}
+
+func (overflow) Check(_ context.Context, tx *domain.Tx, _, to *domain.User) Verdict {
+	if math.MaxInt64-tx.Value < to.Balance {
+		return Verdict{Decision: Deny, Reason: ReasonTooMuch, Message: fmt.Sprintf("user id=%s", to.ID)}
+	}
+
+	return allow()
+}
+
+type maxValue struct {
+	limit    domain.Balance
+	decision Decision
+}
+
+// MaxValue makes the decision about a transfer of more than limit.
+func MaxValue(limit domain.Balance, decision Decision) Rule {
+	return maxValue{limit: limit, decision: decision}
+}
+
+func (maxValue) Name() string {
+	return RuleMaxValue
+}
+
+func (r maxValue) Check(_ context.Context, tx *domain.Tx, _, _ *domain.User) Verdict {
+	if tx.Value > r.limit {
+		return Verdict{
+			Decision: r.decision,
+			Reason:   ReasonValueOverLimit,
+			Message:  fmt.Sprintf("value=%d is over %d", tx.Value, r.limit),
+		}
+	}
+
+	return allow()
+}
+
+type blockedUsers struct {
+	users    map[domain.UserID]struct{}
+	decision Decision
+}
+
+// BlockedUsers makes the decision about a transfer from or to any of the users.
+func BlockedUsers(users []domain.UserID, decision Decision) Rule {
+	r := blockedUsers{
+		users:    make(map[domain.UserID]struct{}, len(users)),
+		decision: decision,
+	}
+	for _, id := range users {
+		r.users[id] = struct{}{}
+	}
+
+	return r
+}
+
+func (blockedUsers) Name() string {
+	return RuleBlockedUsers
+}
+
+func (r blockedUsers) Check(_ context.Context, _ *domain.Tx, from, to *domain.User) Verdict {
+	for _, user := range []*domain.User{from, to} {
+		if _, ok := r.users[user.ID]; ok {
+			return Verdict{Decision: r.decision, Reason: ReasonBlockedUser, Message: fmt.Sprintf("user id=%s", user.ID)}
+		}
+	}
+
+	return allow()
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+	"github.com/kaz-as/test-transactions/pkg/tracing"
+)
+
+// listLockID is a key of the advisory lock that serializes the rebalances between app instances.
+const listLockID = 20261019150000
+
+type shardsRepo struct {
+	log logger.Interface
+}
+
+func NewRepo(log logger.Interface) domain.PrimaryShardsRepository {
+	return &shardsRepo{
+		log: log,
+	}
+}
+
+func (s *shardsRepo) GetForUpdate(ctx context.Context, tx *sql.Tx, id int) (_ *domain.PrimaryShard, err error) {
+	ctx, span := tracing.Start(ctx, "shardsRepo.GetForUpdate")
+	defer tracing.End(span, &err)
+
+	query := `SELECT id, balance FROM primary_shards WHERE id = $1 FOR NO KEY UPDATE`
+
+	row := tx.QueryRowContext(ctx, query, id)
+
+	shard := domain.PrimaryShard{}
+	err = row.Scan(&shard.ID, (*int64)(&shard.Balance))
+	if err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	return &shard, nil
+}
+
+func (s *shardsRepo) ListForUpdate(ctx context.Context, tx *sql.Tx) (_ []domain.PrimaryShard, err error) {
+	ctx, span := tracing.Start(ctx, "shardsRepo.ListForUpdate")
+	defer tracing.End(span, &err)
+
+	// The row locks cover only the shards that exist: without the advisory lock, the callers creating the same
+	// missing shards would each save them from their own reads, and the last commit would overwrite the others.
+	// Taken first, it also lets the query below see the shards of the previous holder at read committed.
+	_, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, listLockID)
+	if err != nil {
+		return nil, fmt.Errorf("advisory lock: %w", err)
+	}
+
+	query := `SELECT id, balance FROM primary_shards ORDER BY id FOR NO KEY UPDATE`
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			s.log.Ctx(ctx).Error("close rows", "error", err)
+		}
+	}()
+
+	var res []domain.PrimaryShard
+	for rows.Next() {
+		shard := domain.PrimaryShard{}
+		err = rows.Scan(&shard.ID, (*int64)(&shard.Balance))
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		res = append(res, shard)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+
+	return res, nil
+}
+
+func (s *shardsRepo) Save(ctx context.Context, tx *sql.Tx, shard *domain.PrimaryShard) (err error) {
+	ctx, span := tracing.Start(ctx, "shardsRepo.Save")
+	defer tracing.End(span, &err)
+
+	query := `
+INSERT INTO primary_shards (id, balance) VALUES ($1, $2)
+ON CONFLICT (id) DO UPDATE SET balance = excluded.balance`
+
+	_, err = tx.ExecContext(ctx, query, shard.ID, int64(shard.Balance))
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	return nil
+}
+
+func (s *shardsRepo) Total(ctx context.Context, tx *sql.Tx) (_ domain.Balance, err error) {
+	ctx, span := tracing.Start(ctx, "shardsRepo.Total")
+	defer tracing.End(span, &err)
+
+	query := `SELECT COALESCE(SUM(balance), 0)::BIGINT FROM primary_shards`
+
+	var total int64
+	err = tx.QueryRowContext(ctx, query).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("scan: %w", err)
+	}
+
+	return domain.Balance(total), nil
+}
@@ -0,0 +1,77 @@
+package snapshots
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+type Taker interface {
+	TakeSnapshots(ctx context.Context, upTo time.Time) (int64, error)
+	// Now is the time of the clock the transactions are stamped with.
+	Now() time.Time
+}
+
+// Job periodically snapshots balances, so that balance queries do not need to scan the whole history.
+type Job struct {
+	log      logger.Interface
+	taker    Taker
+	interval time.Duration
+	lag      time.Duration
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewJob(log logger.Interface, taker Taker, interval, lag time.Duration) *Job {
+	return &Job{
+		log:      log,
+		taker:    taker,
+		interval: interval,
+		lag:      lag,
+		stop:     make(chan struct{}),
+	}
+}
+
+func (j *Job) Start() {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			j.take()
+
+			select {
+			case <-j.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop waits for the running snapshot to finish.
+func (j *Job) Stop() {
+	close(j.stop)
+	j.wg.Wait()
+}
+
+func (j *Job) take() {
+	ctx, cancel := context.WithTimeout(context.Background(), j.interval)
+	defer cancel()
+
+	upTo := j.taker.Now().Add(-j.lag)
+
+	taken, err := j.taker.TakeSnapshots(ctx, upTo)
+	if err != nil {
+		j.log.Error("snapshots failed", "up_to", upTo, "error", err)
+		return
+	}
+
+	j.log.Info("snapshots taken", "up_to", upTo, "taken", taken)
+}
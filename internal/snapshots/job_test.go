@@ -0,0 +1,31 @@
+package snapshots
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kaz-as/test-transactions/pkg/clock"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+type fakeTaker struct {
+	*clock.Fake
+	upTo []time.Time
+}
+
+func (f *fakeTaker) TakeSnapshots(_ context.Context, upTo time.Time) (int64, error) {
+	f.upTo = append(f.upTo, upTo)
+	return 1, nil
+}
+
+func TestTakeUsesClockOfTaker(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	taker := &fakeTaker{Fake: clock.NewFake(now)}
+
+	NewJob(logger.Nop(), taker, time.Minute, 5*time.Minute).take()
+
+	assert.Equal(t, []time.Time{now.Add(-5 * time.Minute)}, taker.upTo)
+}
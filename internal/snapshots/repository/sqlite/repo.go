@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/kaz-as/test-transactions/domain"
+	sqlitedb "github.com/kaz-as/test-transactions/internal/sqlite"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+	"github.com/kaz-as/test-transactions/pkg/tracing"
+)
+
+type snapshotRepo struct {
+	log logger.Interface
+}
+
+func NewRepo(log logger.Interface) domain.SnapshotsRepository {
+	return &snapshotRepo{
+		log: log,
+	}
+}
+
+// Take relies on every previous run to have snapshotted all the users with transactions up to its moment,
+// so only the transactions after the latest snapshot are scanned. The runs need no lock of their own:
+// the write transaction holds the write lock of the DB.
+func (s *snapshotRepo) Take(ctx context.Context, tx *sql.Tx, upTo time.Time) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "snapshotsRepo.Take")
+	defer tracing.End(span, &err)
+
+	query := `
+WITH prev AS (
+    SELECT COALESCE(MAX(taken_at), ?2) AS taken_at FROM balance_snapshots
+),
+moves AS (
+    SELECT t."to" AS user_id, t.value AS delta
+    FROM transactions t, prev
+    WHERE t.timestamp > prev.taken_at AND t.timestamp <= ?1
+    UNION ALL
+    SELECT t."from", -t.value
+    FROM transactions t, prev
+    WHERE t.timestamp > prev.taken_at AND t.timestamp <= ?1
+)
+INSERT INTO balance_snapshots (user_id, taken_at, balance)
+SELECT m.user_id, ?1, COALESCE((
+    SELECT s.balance
+    FROM balance_snapshots s
+    WHERE s.user_id = m.user_id
+    ORDER BY s.taken_at DESC
+    LIMIT 1
+), 0) + SUM(m.delta)
+FROM moves m
+GROUP BY m.user_id
+ON CONFLICT (user_id, taken_at) DO NOTHING`
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("prepare: %w", err)
+	}
+	defer func() {
+		err := stmt.Close()
+		if err != nil {
+			s.log.Ctx(ctx).Error("close stmt", "error", err)
+		}
+	}()
+
+	res, err := stmt.ExecContext(ctx, sqlitedb.Micros(upTo), sqlitedb.NegInfinity)
+	if err != nil {
+		return 0, fmt.Errorf("exec: %w", err)
+	}
+
+	taken, _ := res.RowsAffected()
+	return taken, nil
+}
+
+func (s *snapshotRepo) BalanceAt(ctx context.Context, tx *sql.Tx, userID domain.UserID, at time.Time) (_ domain.Balance, err error) {
+	ctx, span := tracing.Start(ctx, "snapshotsRepo.BalanceAt")
+	defer tracing.End(span, &err)
+
+	query := `
+WITH last AS (
+    SELECT taken_at, balance
+    FROM balance_snapshots
+    WHERE user_id = ?1 AND taken_at <= ?2
+    ORDER BY taken_at DESC
+    LIMIT 1
+)
+SELECT
+    COALESCE((SELECT balance FROM last), 0)
+    + COALESCE((
+        SELECT SUM(value) FROM transactions
+        WHERE "to" = ?1 AND timestamp > COALESCE((SELECT taken_at FROM last), ?3) AND timestamp <= ?2
+    ), 0)
+    - COALESCE((
+        SELECT SUM(value) FROM transactions
+        WHERE "from" = ?1 AND timestamp > COALESCE((SELECT taken_at FROM last), ?3) AND timestamp <= ?2
+    ), 0)`
+
+	row := tx.QueryRowContext(ctx, query, string(userID), sqlitedb.Micros(at), sqlitedb.NegInfinity)
+
+	var balance int64
+	err = row.Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("scan: %w", err)
+	}
+
+	return domain.Balance(balance), nil
+}
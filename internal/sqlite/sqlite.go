@@ -0,0 +1,81 @@
+// Package sqlite opens the SQLite DB and converts the timestamps kept in it.
+//
+// Every write transaction begins with BEGIN IMMEDIATE: it takes the write lock of the whole DB up front,
+// so the rows read by a transaction cannot be changed by another one until it ends. This stands for
+// the row locks taken by SELECT ... FOR NO KEY UPDATE on Postgres. Read-only transactions begin deferred
+// and read a consistent snapshot in WAL mode, without blocking the writers.
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// NegInfinity is the timestamp of the genesis snapshot, the same as -infinity on Postgres.
+const NegInfinity int64 = math.MinInt64
+
+// DSN returns the connection string of the DB file. A busy writer is waited for up to busyTimeout.
+func DSN(path string, busyTimeout time.Duration) string {
+	q := url.Values{}
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Add("_pragma", "journal_mode(WAL)")
+	q.Add("_pragma", "synchronous(NORMAL)")
+	q.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()))
+	q.Set("_txlock", "immediate")
+
+	return "file:" + path + "?" + q.Encode()
+}
+
+// Open opens the DB file, creating it if needed.
+func Open(path string, busyTimeout time.Duration) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", DSN(path, busyTimeout))
+	if err != nil {
+		return nil, fmt.Errorf("db open: %s", err)
+	}
+
+	db.SetConnMaxIdleTime(time.Minute * 3)
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+
+	err = db.Ping()
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("db ping: %s", err)
+	}
+
+	return db, nil
+}
+
+// IsBusy reports whether the DB transaction has given up waiting for the write lock after the busy timeout.
+func IsBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	// the extended codes keep the primary one in the lowest byte
+	return sqliteErr.Code()&0xff == sqlite3.SQLITE_BUSY
+}
+
+// Micros returns the timestamp as it is kept in the DB: Unix microseconds.
+func Micros(t time.Time) int64 {
+	return t.UnixMicro()
+}
+
+// Time returns the timestamp kept in the DB in UTC.
+func Time(micros int64) time.Time {
+	return time.UnixMicro(micros).UTC()
+}
+
+// IsError reports whether the error is reported by SQLite, as opposed to e.g. a failure of the connection.
+func IsError(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr)
+}
@@ -4,26 +4,72 @@ import (
 	"context"
 	"database/sql"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
+
 	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/pkg/clock"
 	"github.com/kaz-as/test-transactions/pkg/logger"
+	"github.com/kaz-as/test-transactions/pkg/tracing"
 )
 
 type txRepo struct {
 	log logger.Interface
+	// clock is nil if the DB stamps the transactions with the timestamp function
+	clock     clock.Clock
+	timestamp TimestampFunc
 }
 
-func NewRepo(log logger.Interface) domain.TxRepository {
-	return &txRepo{
-		log: log,
+// TimestampFunc is a function of the DB clock.
+type TimestampFunc string
+
+const (
+	// TransactionTimestamp is the start of the DB transaction, the same for all its transactions.
+	TransactionTimestamp TimestampFunc = "now()"
+	// StatementTimestamp is the current time.
+	StatementTimestamp TimestampFunc = "clock_timestamp()"
+)
+
+type Option func(*txRepo)
+
+// DBTimestamps stamps the transactions with the DB clock instead of the clock of the repository, so that
+// the timestamps written by different app servers are ordered alike.
+func DBTimestamps(f TimestampFunc) Option {
+	return func(t *txRepo) {
+		t.clock = nil
+		t.timestamp = f
 	}
 }
 
-func (t *txRepo) Store(ctx context.Context, tx *sql.Tx, transaction *domain.Tx) error {
-	query := `INSERT INTO transactions (id, "from", "to", value, timestamp) VALUES ($1, $2, $3, $4, $5);`
+func NewRepo(log logger.Interface, clk clock.Clock, opts ...Option) domain.TxRepository {
+	t := &txRepo{
+		log:       log,
+		clock:     clk,
+		timestamp: TransactionTimestamp,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+func (t *txRepo) Store(ctx context.Context, tx *sql.Tx, transaction *domain.Tx) (err error) {
+	ctx, span := tracing.Start(ctx, "txRepo.Store")
+	defer tracing.End(span, &err)
+
+	// the DB stamps the transaction if the repository does not pass the timestamp
+	query := `
+INSERT INTO transactions (id, "from", "to", value, timestamp, description, external_reference, metadata,
+    from_seq, from_balance, to_seq, to_balance)
+VALUES ($1, $2, $3, $4, COALESCE($5, ` + string(t.timestamp) + `), $6, $7, $8, $9, $10, $11, $12)
+RETURNING timestamp`
 
 	src := rand.New(rand.NewSource(time.Now().UnixNano() + int64(transaction.Value)))
 	uid, err := generateUID(src)
@@ -31,26 +77,193 @@ func (t *txRepo) Store(ctx context.Context, tx *sql.Tx, transaction *domain.Tx)
 		return fmt.Errorf("generate uid: %w", err)
 	}
 
-	stmt, err := tx.PrepareContext(ctx, query)
+	metadata, err := encodeMetadata(transaction.Metadata)
+	if err != nil {
+		return err
+	}
+
+	var timeNow *time.Time
+	if t.clock != nil {
+		now := t.clock.Now()
+		timeNow = &now
+	}
+
+	var timestamp time.Time
+	err = tx.QueryRowContext(ctx, query, uid, string(transaction.From), string(transaction.To), int64(transaction.Value),
+		timeNow, transaction.Description, reference(transaction.ExternalReference), metadata,
+		transaction.FromSeq, int64(transaction.FromBalance), transaction.ToSeq, int64(transaction.ToBalance),
+	).Scan(&timestamp)
+	if err != nil {
+		return fmt.Errorf("exec: %w", storeError(err))
+	}
+
+	transaction.ID = uid
+	transaction.Timestamp = timestamp
+	return nil
+}
+
+// Postgres error code and the index of an external reference taken by a concurrent transaction
+// the sender's lock did not stop, like a transfer of the primary user, whose shards are locked, not the user.
+const (
+	codeUniqueViolation = "23505"
+	referenceIndex      = "transactions_from_external_reference_idx"
+)
+
+// storeError tells a taken external reference from the other failures of storing a transaction.
+func storeError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == codeUniqueViolation && pgErr.ConstraintName == referenceIndex {
+		return fmt.Errorf("%w: %s", domain.ErrDuplicateReference, err)
+	}
+
+	return err
+}
+
+func (t *txRepo) GetByReference(ctx context.Context, tx *sql.Tx, from domain.UserID, reference string) (
+	_ *domain.Tx,
+	err error,
+) {
+	ctx, span := tracing.Start(ctx, "txRepo.GetByReference")
+	defer tracing.End(span, &err)
+
+	query := `SELECT ` + columns + ` FROM transactions WHERE "from" = $1 AND external_reference = $2`
+
+	transaction, err := scanTx(tx.QueryRowContext(ctx, query, string(from), reference))
 	if err != nil {
-		return fmt.Errorf("prepare: %w", err)
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	return transaction, nil
+}
+
+func (t *txRepo) ListByUser(
+	ctx context.Context,
+	tx *sql.Tx,
+	userID domain.UserID,
+	filter domain.TxFilter,
+	limit int,
+) (_ []domain.Tx, err error) {
+	ctx, span := tracing.Start(ctx, "txRepo.ListByUser")
+	defer tracing.End(span, &err)
+
+	// metadata @> matches the string values only, as the filter has no other ones
+	query := `
+SELECT ` + columns + ` FROM (
+    (SELECT * FROM transactions WHERE "from" = $1 AND ($3 = '' OR external_reference = $3) AND metadata @> $4::jsonb
+     ORDER BY timestamp DESC, id LIMIT $2)
+    UNION ALL
+    (SELECT * FROM transactions WHERE "to" = $1 AND ($3 = '' OR external_reference = $3) AND metadata @> $4::jsonb
+     ORDER BY timestamp DESC, id LIMIT $2)
+) t
+ORDER BY timestamp DESC, id
+LIMIT $2`
+
+	metadataFilter := []byte("{}")
+	if len(filter.Metadata) > 0 {
+		metadataFilter, err = json.Marshal(filter.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("encode metadata filter: %w", err)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, query, string(userID), limit, filter.ExternalReference, string(metadataFilter))
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
 	}
 	defer func() {
-		err := stmt.Close()
+		err := rows.Close()
 		if err != nil {
-			t.log.Error("close stmt: %s", err)
+			t.log.Ctx(ctx).Error("close rows", "error", err)
 		}
 	}()
 
-	timeNow := time.Now()
-	_, err = stmt.ExecContext(ctx, uid, string(transaction.From), string(transaction.To), int64(transaction.Value), timeNow)
+	var res []domain.Tx
+	for rows.Next() {
+		transaction, err := scanTx(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		res = append(res, *transaction)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+
+	return res, nil
+}
+
+const columns = `id, "from", "to", value, timestamp, description, external_reference, metadata,
+    from_seq, from_balance, to_seq, to_balance`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTx(row scanner) (*domain.Tx, error) {
+	var (
+		transaction domain.Tx
+		reference   sql.NullString
+		metadata    []byte
+	)
+
+	err := row.Scan(
+		&transaction.ID,
+		(*string)(&transaction.From),
+		(*string)(&transaction.To),
+		(*int64)(&transaction.Value),
+		&transaction.Timestamp,
+		&transaction.Description,
+		&reference,
+		&metadata,
+		&transaction.FromSeq,
+		(*int64)(&transaction.FromBalance),
+		&transaction.ToSeq,
+		(*int64)(&transaction.ToBalance),
+	)
 	if err != nil {
-		return fmt.Errorf("exec: %w", err)
+		return nil, err
 	}
 
-	transaction.ID = uid
-	transaction.Timestamp = timeNow
-	return nil
+	transaction.ExternalReference = reference.String
+	transaction.Metadata, err = decodeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
+
+// reference stores an empty reference as NULL, so it is not unique.
+func reference(ref string) sql.NullString {
+	return sql.NullString{String: ref, Valid: ref != ""}
+}
+
+func encodeMetadata(metadata map[string]interface{}) (string, error) {
+	if len(metadata) == 0 {
+		return "{}", nil
+	}
+
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("encode metadata: %w", err)
+	}
+
+	return string(b), nil
+}
+
+// decodeMetadata returns nil for an empty object.
+func decodeMetadata(b []byte) (map[string]interface{}, error) {
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(b, &metadata); err != nil {
+		return nil, fmt.Errorf("decode metadata: %w", err)
+	}
+
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+
+	return metadata, nil
 }
 
 func generateUID(src *rand.Rand) (string, error) {
@@ -0,0 +1,23 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kaz-as/test-transactions/domain"
+)
+
+func TestStoreError(t *testing.T) {
+	taken := &pgconn.PgError{Code: codeUniqueViolation, ConstraintName: referenceIndex}
+	assert.ErrorIs(t, storeError(fmt.Errorf("scan: %w", taken)), domain.ErrDuplicateReference)
+
+	other := &pgconn.PgError{Code: codeUniqueViolation, ConstraintName: "transactions_pkey"}
+	assert.Equal(t, other, storeError(other), "only the reference index is a duplicate reference")
+
+	err := errors.New("conn closed")
+	assert.Equal(t, err, storeError(err))
+}
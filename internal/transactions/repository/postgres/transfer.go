@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/pkg/tracing"
+)
+
+// Transfer moves the value between two users, neither of them primary, in a single statement: it locks both users
+// in the order of their ids, checks the balances, the precondition of the sender and the external reference,
+// updates the users and stores the transaction numbered among theirs. If any user is missing or any check fails,
+// nothing is changed and sql.ErrNoRows is returned. A reference stored by a transfer committed while this one
+// waited for the locks is not seen by the check, but by the unique index: domain.ErrDuplicateReference
+// is returned then, and the DB transaction is aborted.
+func (t *txRepo) Transfer(ctx context.Context, tx *sql.Tx, transaction *domain.Tx) (
+	from *domain.User,
+	to *domain.User,
+	err error,
+) {
+	ctx, span := tracing.Start(ctx, "txRepo.Transfer")
+	defer tracing.End(span, &err)
+
+	// The CTEs see the snapshot taken before the locks, but the rows locked by locked are updated
+	// in their latest versions. The value is never negative here, the policy checks it first; the statement
+	// rejects it anyway, and GREATEST keeps the subtraction from overflowing whatever order the checks run in.
+	query := `
+WITH locked AS (
+    SELECT id, balance, version FROM users WHERE id IN ($2, $3) ORDER BY id FOR NO KEY UPDATE
+), checked AS (
+    SELECT true AS ok
+    FROM locked f, locked t
+    WHERE f.id = $2 AND t.id = $3
+      AND $4::bigint >= 0
+      AND f.balance >= $4::bigint AND t.balance <= 9223372036854775807 - GREATEST($4::bigint, 0)
+      AND ($9::bigint IS NULL OR f.version = $9::bigint)
+      AND ($10::bigint IS NULL OR f.balance = $10::bigint)
+      AND NOT EXISTS (SELECT 1 FROM transactions WHERE "from" = $2 AND external_reference = $7::text)
+), moved AS (
+    UPDATE users u
+    SET balance = CASE WHEN u.id = $2 THEN u.balance - $4::bigint ELSE u.balance + $4::bigint END,
+        seq = u.seq + 1,
+        version = u.version + 1
+    FROM checked
+    WHERE u.id IN ($2, $3)
+    RETURNING u.id, u.balance, u.seq, u.version
+), stored AS (
+    INSERT INTO transactions (id, "from", "to", value, timestamp, description, external_reference, metadata,
+        from_seq, from_balance, to_seq, to_balance)
+    SELECT $1::text, f.id, t.id, $4::bigint, COALESCE($5, ` + string(t.timestamp) + `), $6::text, $7::text, $8::jsonb,
+        f.seq, f.balance, t.seq, t.balance
+    FROM moved f, moved t
+    WHERE f.id = $2 AND t.id = $3
+    RETURNING timestamp
+)
+SELECT f.balance, f.seq, f.version, t.balance, t.seq, t.version, s.timestamp
+FROM moved f, moved t, stored s
+WHERE f.id = $2 AND t.id = $3`
+
+	src := rand.New(rand.NewSource(time.Now().UnixNano() + int64(transaction.Value)))
+	uid, err := generateUID(src)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate uid: %w", err)
+	}
+
+	metadata, err := encodeMetadata(transaction.Metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var timeNow *time.Time
+	if t.clock != nil {
+		now := t.clock.Now()
+		timeNow = &now
+	}
+
+	var expectedBalance *int64
+	if transaction.Expect.Balance != nil {
+		b := int64(*transaction.Expect.Balance)
+		expectedBalance = &b
+	}
+
+	from = &domain.User{ID: transaction.From}
+	to = &domain.User{ID: transaction.To}
+
+	var timestamp time.Time
+	err = tx.QueryRowContext(ctx, query, uid, string(transaction.From), string(transaction.To),
+		int64(transaction.Value), timeNow, transaction.Description, reference(transaction.ExternalReference), metadata,
+		transaction.Expect.Version, expectedBalance,
+	).Scan(
+		(*int64)(&from.Balance), &from.Seq, &from.Version,
+		(*int64)(&to.Balance), &to.Seq, &to.Version,
+		&timestamp,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("scan: %w", storeError(err))
+	}
+
+	transaction.ID = uid
+	transaction.Timestamp = timestamp
+	transaction.FromSeq, transaction.FromBalance = from.Seq, from.Balance
+	transaction.ToSeq, transaction.ToBalance = to.Seq, to.Balance
+	return from, to, nil
+}
@@ -0,0 +1,240 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/kaz-as/test-transactions/domain"
+	sqlitedb "github.com/kaz-as/test-transactions/internal/sqlite"
+	"github.com/kaz-as/test-transactions/pkg/clock"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+	"github.com/kaz-as/test-transactions/pkg/tracing"
+)
+
+type txRepo struct {
+	log   logger.Interface
+	clock clock.Clock
+}
+
+func NewRepo(log logger.Interface, clk clock.Clock) domain.TxRepository {
+	return &txRepo{
+		log:   log,
+		clock: clk,
+	}
+}
+
+func (t *txRepo) Store(ctx context.Context, tx *sql.Tx, transaction *domain.Tx) (err error) {
+	ctx, span := tracing.Start(ctx, "txRepo.Store")
+	defer tracing.End(span, &err)
+
+	query := `
+INSERT INTO transactions (id, "from", "to", value, timestamp, description, external_reference, metadata,
+    from_seq, from_balance, to_seq, to_balance)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12)`
+
+	src := rand.New(rand.NewSource(time.Now().UnixNano() + int64(transaction.Value)))
+	uid, err := generateUID(src)
+	if err != nil {
+		return fmt.Errorf("generate uid: %w", err)
+	}
+
+	metadata, err := encodeMetadata(transaction.Metadata)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("prepare: %w", err)
+	}
+	defer func() {
+		err := stmt.Close()
+		if err != nil {
+			t.log.Ctx(ctx).Error("close stmt", "error", err)
+		}
+	}()
+
+	// the DB keeps microseconds, so the returned timestamp is the same as the stored one
+	timeNow := sqlitedb.Time(sqlitedb.Micros(t.clock.Now()))
+
+	_, err = stmt.ExecContext(ctx, uid, string(transaction.From), string(transaction.To), int64(transaction.Value),
+		sqlitedb.Micros(timeNow), transaction.Description, reference(transaction.ExternalReference), metadata,
+		transaction.FromSeq, int64(transaction.FromBalance), transaction.ToSeq, int64(transaction.ToBalance))
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	transaction.ID = uid
+	transaction.Timestamp = timeNow
+	return nil
+}
+
+func (t *txRepo) GetByReference(ctx context.Context, tx *sql.Tx, from domain.UserID, reference string) (
+	_ *domain.Tx,
+	err error,
+) {
+	ctx, span := tracing.Start(ctx, "txRepo.GetByReference")
+	defer tracing.End(span, &err)
+
+	query := `SELECT ` + columns + ` FROM transactions WHERE "from" = ?1 AND external_reference = ?2`
+
+	transaction, err := scanTx(tx.QueryRowContext(ctx, query, string(from), reference))
+	if err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	return transaction, nil
+}
+
+func (t *txRepo) ListByUser(
+	ctx context.Context,
+	tx *sql.Tx,
+	userID domain.UserID,
+	filter domain.TxFilter,
+	limit int,
+) (_ []domain.Tx, err error) {
+	ctx, span := tracing.Start(ctx, "txRepo.ListByUser")
+	defer tracing.End(span, &err)
+
+	// every key of the filter must be in the metadata with the same string value
+	match := `(?3 = '' OR external_reference = ?3) AND NOT EXISTS (
+        SELECT 1 FROM json_each(?4) f WHERE NOT EXISTS (
+            SELECT 1 FROM json_each(transactions.metadata) m
+            WHERE m.key = f.key AND m.type = 'text' AND m.value = f.value))`
+
+	query := `
+SELECT ` + columns + ` FROM (
+    SELECT * FROM (SELECT * FROM transactions WHERE "from" = ?1 AND ` + match + `
+                   ORDER BY timestamp DESC, id LIMIT ?2)
+    UNION ALL
+    SELECT * FROM (SELECT * FROM transactions WHERE "to" = ?1 AND ` + match + `
+                   ORDER BY timestamp DESC, id LIMIT ?2)
+)
+ORDER BY timestamp DESC, id
+LIMIT ?2`
+
+	metadataFilter := []byte("{}")
+	if len(filter.Metadata) > 0 {
+		metadataFilter, err = json.Marshal(filter.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("encode metadata filter: %w", err)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, query, string(userID), limit, filter.ExternalReference, string(metadataFilter))
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			t.log.Ctx(ctx).Error("close rows", "error", err)
+		}
+	}()
+
+	var res []domain.Tx
+	for rows.Next() {
+		transaction, err := scanTx(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		res = append(res, *transaction)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+
+	return res, nil
+}
+
+const columns = `id, "from", "to", value, timestamp, description, external_reference, metadata,
+    from_seq, from_balance, to_seq, to_balance`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTx(row scanner) (*domain.Tx, error) {
+	var (
+		transaction domain.Tx
+		timestamp   int64
+		reference   sql.NullString
+		metadata    string
+	)
+
+	err := row.Scan(
+		&transaction.ID,
+		(*string)(&transaction.From),
+		(*string)(&transaction.To),
+		(*int64)(&transaction.Value),
+		&timestamp,
+		&transaction.Description,
+		&reference,
+		&metadata,
+		&transaction.FromSeq,
+		(*int64)(&transaction.FromBalance),
+		&transaction.ToSeq,
+		(*int64)(&transaction.ToBalance),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction.Timestamp = sqlitedb.Time(timestamp)
+	transaction.ExternalReference = reference.String
+	transaction.Metadata, err = decodeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
+
+// reference stores an empty reference as NULL, so it is not unique.
+func reference(ref string) sql.NullString {
+	return sql.NullString{String: ref, Valid: ref != ""}
+}
+
+func encodeMetadata(metadata map[string]interface{}) (string, error) {
+	if len(metadata) == 0 {
+		return "{}", nil
+	}
+
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("encode metadata: %w", err)
+	}
+
+	return string(b), nil
+}
+
+// decodeMetadata returns nil for an empty object.
+func decodeMetadata(s string) (map[string]interface{}, error) {
+	var metadata map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &metadata); err != nil {
+		return nil, fmt.Errorf("decode metadata: %w", err)
+	}
+
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+
+	return metadata, nil
+}
+
+func generateUID(src *rand.Rand) (string, error) {
+	b := make([]byte, 32)
+
+	if _, err := src.Read(b); err != nil {
+		return "", err
+	}
+
+	encoded := hex.EncodeToString(b)
+	return encoded[:64], nil
+}
@@ -0,0 +1,269 @@
+// Package batching commits the transfers in groups: the ones arriving within a short window are applied in one
+// DB transaction, so they pay for one commit instead of one each.
+package batching
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/usecases/general"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+	"github.com/kaz-as/test-transactions/pkg/tracing"
+)
+
+var ErrStopped = errors.New("batching stopped")
+
+// Batcher applies the transfers in one DB transaction, see general.UseCase.CreateTxBatch.
+type Batcher interface {
+	CreateTxBatch(ctx context.Context, txs []*domain.Tx) ([]general.TxResult, error)
+}
+
+type request struct {
+	ctx    context.Context
+	tx     *domain.Tx
+	at     time.Time
+	result chan general.TxResult
+}
+
+// UseCase batches the transfers between the users; the rest of the calls, the transfers of the primary user
+// included, go to the wrapped use case as they are.
+type UseCase struct {
+	domain.UseCase
+
+	log     logger.Interface
+	batcher Batcher
+	window  time.Duration
+	maxSize int
+
+	requests chan *request
+	// mu guards stopped against the requests sent at once with the stop
+	mu      sync.RWMutex
+	stopped bool
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+var _ domain.UseCase = (*UseCase)(nil)
+
+// NewUseCase returns the use case collecting the transfers for window after the first one of a batch,
+// up to maxSize of them. Start must be called before it is used.
+func NewUseCase(log logger.Interface, uc domain.UseCase, batcher Batcher, window time.Duration, maxSize int) *UseCase {
+	if maxSize < 1 {
+		maxSize = 1
+	}
+
+	return &UseCase{
+		UseCase:  uc,
+		log:      log,
+		batcher:  batcher,
+		window:   window,
+		maxSize:  maxSize,
+		requests: make(chan *request, maxSize),
+		stop:     make(chan struct{}),
+	}
+}
+
+// CreateTx waits for the batch of the transfer to be committed. A transfer is dropped if its context is done
+// before its batch begins; once it has begun, the result of the batch is waited for, so it is never unknown.
+func (u *UseCase) CreateTx(ctx context.Context, tx *domain.Tx) (domain.Balance, domain.Balance, error) {
+	if tx.From == general.PrimaryUserID || tx.To == general.PrimaryUserID {
+		return u.UseCase.CreateTx(ctx, tx)
+	}
+
+	r := &request{ctx: ctx, tx: tx, at: time.Now(), result: make(chan general.TxResult, 1)}
+
+	u.mu.RLock()
+	if u.stopped {
+		u.mu.RUnlock()
+		return 0, 0, ErrStopped
+	}
+
+	select {
+	case u.requests <- r:
+		u.mu.RUnlock()
+	case <-ctx.Done():
+		u.mu.RUnlock()
+		return 0, 0, ctx.Err()
+	}
+
+	res := <-r.result
+	return res.NewBalanceFrom, res.NewBalanceTo, res.Err
+}
+
+func (u *UseCase) Start() {
+	u.wg.Add(1)
+	go func() {
+		defer u.wg.Done()
+		u.run()
+	}()
+}
+
+// Stop applies the transfers already sent; the servers must be stopped first, so that no more are sent.
+func (u *UseCase) Stop() {
+	u.mu.Lock()
+	u.stopped = true
+	u.mu.Unlock()
+
+	close(u.stop)
+	u.wg.Wait()
+}
+
+func (u *UseCase) run() {
+	var pending []*request
+
+	for {
+		if len(pending) == 0 {
+			select {
+			case r := <-u.requests:
+				pending = append(pending, r)
+			case <-u.stop:
+				u.drain()
+				return
+			}
+		}
+
+		// the transfers left over from the previous batch have waited long enough
+		timer := time.NewTimer(time.Until(pending[0].at.Add(u.window)))
+	collect:
+		for len(pending) < u.maxSize {
+			select {
+			case r := <-u.requests:
+				pending = append(pending, r)
+			case <-timer.C:
+				break collect
+			case <-u.stop:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		var batch []*request
+		batch, pending = split(pending, u.maxSize)
+		u.apply(batch)
+	}
+}
+
+// drain applies the transfers sent before the stop.
+func (u *UseCase) drain() {
+	var pending []*request
+	for {
+		select {
+		case r := <-u.requests:
+			pending = append(pending, r)
+		default:
+			for len(pending) > 0 {
+				var batch []*request
+				batch, pending = split(pending, u.maxSize)
+				u.apply(batch)
+			}
+			return
+		}
+	}
+}
+
+// split takes up to maxSize transfers with no user in common into the batch, in the order they came.
+// A transfer is left for the next batch if a user of it is in the batch or in a transfer left before,
+// so the transfers of every user are applied in the order they came. The dropped transfers are resolved.
+func split(pending []*request, maxSize int) (batch, rest []*request) {
+	taken := make(map[domain.UserID]bool, 2*len(pending))
+
+	for _, r := range pending {
+		if err := r.ctx.Err(); err != nil {
+			r.result <- general.TxResult{Err: err}
+			continue
+		}
+
+		if len(batch) < maxSize && !taken[r.tx.From] && !taken[r.tx.To] {
+			batch = append(batch, r)
+		} else {
+			rest = append(rest, r)
+		}
+		taken[r.tx.From], taken[r.tx.To] = true, true
+	}
+
+	return batch, rest
+}
+
+func (u *UseCase) apply(batch []*request) {
+	if len(batch) == 0 {
+		return
+	}
+
+	txs := make([]*domain.Tx, len(batch))
+	for i, r := range batch {
+		txs[i] = r.tx
+	}
+
+	ctx, stop := batchContext(batch)
+	var err error
+	ctx, span := tracing.Start(ctx, "batching.apply",
+		trace.WithLinks(links(batch)...), trace.WithAttributes(attribute.Int("batch.size", len(batch))))
+	defer func() {
+		tracing.End(span, &err)
+		stop()
+	}()
+
+	results, err := u.batcher.CreateTxBatch(ctx, txs)
+	if err != nil {
+		u.log.Ctx(ctx).Error("transfer batch failed", "size", len(batch), "error", err)
+	}
+
+	for i, r := range batch {
+		// the results of a failed batch are the outcomes found out after a commit with no answer
+		if err != nil && results == nil {
+			r.result <- general.TxResult{Err: err}
+			continue
+		}
+		r.result <- results[i]
+	}
+}
+
+// batchContext returns the context of the batch: it carries the request ids to log and is canceled
+// once all the requests are done, canceled or past their deadlines, as no one waits for the batch then.
+// Until then the batch is limited by the timeout of the use case alone, not by the earliest deadline,
+// so a request about to time out does not fail the others.
+func batchContext(batch []*request) (context.Context, func()) {
+	var ids []interface{}
+	for _, r := range batch {
+		if id, ok := logger.Value(r.ctx, "request_id"); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(logger.ContextWith(context.Background(), "request_ids", ids))
+
+	remaining := int32(len(batch))
+	stops := make([]func() bool, len(batch))
+	for i, r := range batch {
+		stops[i] = context.AfterFunc(r.ctx, func() {
+			if atomic.AddInt32(&remaining, -1) == 0 {
+				cancel()
+			}
+		})
+	}
+
+	return ctx, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		cancel()
+	}
+}
+
+// links links the span of the batch to the spans of its requests.
+func links(batch []*request) []trace.Link {
+	res := make([]trace.Link, 0, len(batch))
+	for _, r := range batch {
+		if link := trace.LinkFromContext(r.ctx); link.SpanContext.IsValid() {
+			res = append(res, link)
+		}
+	}
+	return res
+}
@@ -0,0 +1,229 @@
+package batching
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kaz-as/test-transactions/config"
+	"github.com/kaz-as/test-transactions/domain"
+	auditsqlite "github.com/kaz-as/test-transactions/internal/audit/repository/sqlite"
+	eventssqlite "github.com/kaz-as/test-transactions/internal/events/repository/sqlite"
+	"github.com/kaz-as/test-transactions/internal/migrate"
+	shardssqlite "github.com/kaz-as/test-transactions/internal/shards/repository/sqlite"
+	snapshotssqlite "github.com/kaz-as/test-transactions/internal/snapshots/repository/sqlite"
+	"github.com/kaz-as/test-transactions/internal/sqlite"
+	txsqlite "github.com/kaz-as/test-transactions/internal/transactions/repository/sqlite"
+	"github.com/kaz-as/test-transactions/internal/usecases/general"
+	userssqlite "github.com/kaz-as/test-transactions/internal/users/repository/sqlite"
+	"github.com/kaz-as/test-transactions/pkg/clock"
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+type fakeBatcher struct {
+	mu      sync.Mutex
+	batches [][]domain.UserID
+	// err is returned along with the results, as after a commit with no answer
+	err error
+}
+
+// CreateTxBatch fails the transfers of more than 100.
+func (f *fakeBatcher) CreateTxBatch(_ context.Context, txs []*domain.Tx) ([]general.TxResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var senders []domain.UserID
+	results := make([]general.TxResult, len(txs))
+	for i, tx := range txs {
+		senders = append(senders, tx.From)
+		if tx.Value > 100 {
+			results[i].Err = general.ErrInsufficientBalance
+			continue
+		}
+		results[i] = general.TxResult{NewBalanceFrom: 100 - tx.Value, NewBalanceTo: tx.Value}
+	}
+	f.batches = append(f.batches, senders)
+
+	return results, f.err
+}
+
+func TestSplit(t *testing.T) {
+	req := func(from, to domain.UserID) *request {
+		return &request{ctx: context.Background(), tx: &domain.Tx{From: from, To: to}, result: make(chan general.TxResult, 1)}
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	dropped := req("e", "f")
+	dropped.ctx = canceled
+
+	pending := []*request{req("a", "b"), req("b", "c"), dropped, req("c", "d"), req("e", "f"), req("g", "h")}
+
+	batch, rest := split(pending, 2)
+	assert.Equal(t, []*request{pending[0], pending[4]}, batch)
+	assert.Equal(t, []*request{pending[1], pending[3], pending[5]}, rest,
+		"c-d follows b-c left for the next batch, g-h is over the size")
+	assert.ErrorIs(t, (<-dropped.result).Err, context.Canceled)
+}
+
+func TestCreateTx(t *testing.T) {
+	batcher := &fakeBatcher{}
+	uc := NewUseCase(logger.Nop(), nil, batcher, 20*time.Millisecond, 10)
+	uc.Start()
+
+	var wg sync.WaitGroup
+	for _, tx := range []domain.Tx{{From: "a", To: "b", Value: 10}, {From: "c", To: "d", Value: 101}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			from, to, err := uc.CreateTx(context.Background(), &tx)
+			if tx.Value > 100 {
+				assert.ErrorIs(t, err, general.ErrInsufficientBalance, "a failed transfer fails alone")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, domain.Balance(90), from)
+			assert.Equal(t, domain.Balance(10), to)
+		}()
+	}
+	wg.Wait()
+
+	uc.Stop()
+	require.Len(t, batcher.batches, 1, "the transfers sent within the window share the batch")
+	assert.ElementsMatch(t, []domain.UserID{"a", "c"}, batcher.batches[0])
+
+	_, _, err := uc.CreateTx(context.Background(), &domain.Tx{From: "a", To: "b", Value: 1})
+	assert.ErrorIs(t, err, ErrStopped)
+}
+
+func TestCommitUnknown(t *testing.T) {
+	batcher := &fakeBatcher{err: general.ErrCommitUnknown}
+	uc := NewUseCase(logger.Nop(), nil, batcher, time.Millisecond, 10)
+	uc.Start()
+	defer uc.Stop()
+
+	from, to, err := uc.CreateTx(context.Background(), &domain.Tx{From: "a", To: "b", Value: 10})
+	require.NoError(t, err, "the outcome found out after the commit is returned, not the error of the batch")
+	assert.Equal(t, domain.Balance(90), from)
+	assert.Equal(t, domain.Balance(10), to)
+}
+
+func TestBatchContext(t *testing.T) {
+	first, cancelFirst := context.WithCancel(logger.ContextWith(context.Background(), "request_id", "1"))
+	second, cancelSecond := context.WithCancel(logger.ContextWith(context.Background(), "request_id", "2"))
+	defer cancelSecond()
+
+	ctx, stop := batchContext([]*request{{ctx: first}, {ctx: second}})
+	defer stop()
+
+	ids, _ := logger.Value(ctx, "request_ids")
+	assert.Equal(t, []interface{}{"1", "2"}, ids)
+
+	cancelFirst()
+	select {
+	case <-ctx.Done():
+		t.Fatal("the batch is canceled while a request waits for it")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	cancelSecond()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the batch is not canceled once no request waits for it")
+	}
+}
+
+// TestCreateTxSQLite batches the transfers of the general use case on SQLite.
+func TestCreateTxSQLite(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "tx.db"), time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	require.NoError(t, migrate.Run(context.Background(), db, config.DriverSQLite, io.Discard, migrate.Up))
+
+	l := logger.Nop()
+	gen := general.NewUseCase(l, db,
+		userssqlite.NewRepo(l),
+		shardssqlite.NewRepo(l),
+		txsqlite.NewRepo(l, clock.System()),
+		snapshotssqlite.NewRepo(l),
+		auditsqlite.NewRepo(l),
+		eventssqlite.NewRepo(l),
+		time.Second,
+	)
+
+	users := make([]domain.UserID, 4)
+	for i := range users {
+		user := domain.User{Balance: 100}
+		require.NoError(t, gen.CreateUser(context.Background(), &user))
+		users[i] = user.ID
+	}
+	a, b, c, d := users[0], users[1], users[2], users[3]
+
+	uc := NewUseCase(l, gen, gen, 20*time.Millisecond, 10)
+	uc.Start()
+
+	tracer := otel.Tracer("test")
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		requests []trace.SpanContext
+	)
+	for _, tx := range []domain.Tx{{From: a, To: b, Value: 10}, {From: c, To: d, Value: 20}, {From: b, To: c, Value: 500}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, span := tracer.Start(context.Background(), "request")
+			defer span.End()
+			mu.Lock()
+			requests = append(requests, span.SpanContext())
+			mu.Unlock()
+
+			from, to, err := uc.CreateTx(ctx, &tx)
+			if tx.Value == 500 {
+				assert.ErrorIs(t, err, general.ErrInsufficientBalance, "a failed transfer fails alone")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 100-tx.Value, from)
+			assert.Equal(t, 100+tx.Value, to)
+		}()
+	}
+	wg.Wait()
+	uc.Stop()
+
+	var sum domain.Balance
+	for _, id := range users {
+		balance, err := gen.GetBalance(context.Background(), id, nil)
+		require.NoError(t, err)
+		sum += balance
+	}
+	assert.Equal(t, domain.Balance(400), sum)
+
+	var linked []trace.SpanContext
+	for _, span := range spans.Ended() {
+		if span.Name() != "batching.apply" {
+			continue
+		}
+		for _, link := range span.Links() {
+			linked = append(linked, link.SpanContext)
+		}
+	}
+	assert.ElementsMatch(t, requests, linked, "the batches are linked to the spans of their requests")
+}
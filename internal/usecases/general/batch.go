@@ -0,0 +1,141 @@
+package general
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/kaz-as/test-transactions/domain"
+)
+
+var ErrPrimaryBatched = errors.New("transfers of the primary user are not batched")
+
+// TxResult is the outcome of a transfer of a batch.
+type TxResult struct {
+	NewBalanceFrom domain.Balance
+	NewBalanceTo   domain.Balance
+	Err            error
+}
+
+// CreateTxBatch applies the transfers in one DB transaction, so they share its commit. The users of all of them
+// are locked first in the order of their ids, then every transfer runs under a savepoint: a failed one is rolled
+// back alone and its result has the error. err is returned only if the DB transaction fails as a whole, e.g. it
+// cannot commit; then no transfer is applied. The exception is a commit with no answer, ErrCommitUnknown:
+// the results are returned along with it, see resolveBatch. The transfers of the primary user fail
+// with ErrPrimaryBatched, as they may need to lock all its shards.
+func (u *UseCase) CreateTxBatch(ctx context.Context, txs []*domain.Tx) (results []TxResult, err error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, u.ctxTimeout)
+	defer cancel()
+
+	err = u.inTx(ctxTimeout, OpCreateTxBatch, &sql.TxOptions{Isolation: sql.LevelReadCommitted},
+		func(ctx context.Context, dbTx *sql.Tx) error {
+			results = make([]TxResult, len(txs))
+
+			err := u.lockBatch(ctx, dbTx, txs)
+			if err != nil {
+				return err
+			}
+
+			for i, tx := range txs {
+				results[i], err = u.batchedTx(ctx, dbTx, tx)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	if errors.Is(err, ErrCommitUnknown) {
+		return u.resolveBatch(ctx, txs, results, err), err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// resolveBatch finds out the outcome of every transfer of the batch whose commit got no answer. A transfer failed
+// in the batch was rolled back to its savepoint, so it is not applied either way. An applied one is run again
+// by its external reference, which finds it if the batch was committed, see createTxAgain; one with no reference
+// fails with the error of the commit.
+func (u *UseCase) resolveBatch(ctx context.Context, txs []*domain.Tx, results []TxResult, commitErr error) []TxResult {
+	for i, tx := range txs {
+		if results[i].Err != nil {
+			continue
+		}
+		if tx.ExternalReference == "" {
+			results[i] = TxResult{Err: commitErr}
+			continue
+		}
+
+		u.logger.Ctx(ctx).Warn("transfer batch commit got no answer, running the transfer again",
+			"reference", tx.ExternalReference, "error", commitErr)
+
+		newBalanceFrom, newBalanceTo, err := u.createTxAgain(ctx, tx, nil)
+		results[i] = TxResult{NewBalanceFrom: newBalanceFrom, NewBalanceTo: newBalanceTo, Err: err}
+	}
+
+	return results
+}
+
+// lockBatch locks the users of the transfers in the order of their ids, so the batch cannot deadlock
+// with another one or with a single transfer. The missing users are left to the transfers to fail.
+func (u *UseCase) lockBatch(ctx context.Context, dbTx *sql.Tx, txs []*domain.Tx) error {
+	seen := make(map[domain.UserID]bool, 2*len(txs))
+	var ids []string
+	for _, tx := range txs {
+		for _, id := range []domain.UserID{tx.From, tx.To} {
+			if id != PrimaryUserID && !seen[id] {
+				seen[id] = true
+				ids = append(ids, string(id))
+			}
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		_, err := u.usersRepo.GetForUpdate(ctx, dbTx, domain.UserID(id))
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("lock user id=%s: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// batchedTx runs the transfer under a savepoint. The error is returned, failing the batch, only if the batch
+// must be retried or cannot go on; the error of the transfer itself is in the result.
+func (u *UseCase) batchedTx(ctx context.Context, dbTx *sql.Tx, tx *domain.Tx) (TxResult, error) {
+	if tx.From == PrimaryUserID || tx.To == PrimaryUserID {
+		return TxResult{Err: ErrPrimaryBatched}, nil
+	}
+
+	_, err := dbTx.ExecContext(ctx, `SAVEPOINT transfer`)
+	if err != nil {
+		return TxResult{}, fmt.Errorf("savepoint: %w", err)
+	}
+
+	newBalanceFrom, newBalanceTo, err := u.createTx(ctx, dbTx, tx, false)
+	if err == nil {
+		_, err = dbTx.ExecContext(ctx, `RELEASE SAVEPOINT transfer`)
+		if err != nil {
+			return TxResult{}, fmt.Errorf("release savepoint: %w", err)
+		}
+
+		return TxResult{NewBalanceFrom: newBalanceFrom, NewBalanceTo: newBalanceTo}, nil
+	}
+
+	if IsRetryable(err) || ctx.Err() != nil {
+		return TxResult{}, err
+	}
+
+	_, rbErr := dbTx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT transfer`)
+	if rbErr != nil {
+		return TxResult{}, fmt.Errorf("rollback to savepoint: %w", rbErr)
+	}
+
+	return TxResult{Err: timeoutError(ctx, err)}, nil
+}
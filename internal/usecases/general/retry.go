@@ -0,0 +1,143 @@
+package general
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/kaz-as/test-transactions/domain"
+	sqlitedb "github.com/kaz-as/test-transactions/internal/sqlite"
+)
+
+// Postgres error codes of the transactions that are safe to retry.
+const (
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+)
+
+// RetryPolicy limits the attempts of a DB transaction. The delay before the n-th retry is random
+// in [0, min(MaxDelay, BaseDelay * 2^(n-1))), so the competing transactions do not collide again.
+// All the attempts share the timeout of the use case call.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    100 * time.Millisecond,
+}
+
+// IsRetryable reports whether the DB transaction was aborted by a serialization failure or a deadlock.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	return pgErr.Code == codeSerializationFailure || pgErr.Code == codeDeadlockDetected
+}
+
+// ErrCommitUnknown is returned when the commit of a DB transaction got no answer from the DB, e.g. the connection
+// was lost: the transaction may be committed or not, so it is not retried as a whole.
+var ErrCommitUnknown = errors.New("commit outcome unknown")
+
+// commitError tells a commit the DB has answered, with the transaction rolled back, from the one it has not.
+// The context is checked before the commit, so a context error is of the commit on the way: the driver
+// stops waiting for the answer, but the DB may have committed.
+func commitError(err error) error {
+	var pgErr *pgconn.PgError
+	switch {
+	case err == nil, errors.As(err, &pgErr), sqlitedb.IsError(err):
+		return err
+	case errors.Is(err, sql.ErrTxDone):
+		// database/sql has rolled the transaction back without committing
+		return err
+	default:
+		return fmt.Errorf("%w: %s", ErrCommitUnknown, err)
+	}
+}
+
+// createTxAgain runs the transfer once more after its commit got no answer. The external reference stores it
+// at most once: if the reference is taken by the same transfer, to the same user for the same value,
+// the first commit went through, with the audit record if any, and its result is returned instead.
+// The call has used up most of its timeout by then, so this gets a timeout of its own, and is not canceled
+// with the call: the outcome is logged even for a client that has gone.
+func (u *UseCase) createTxAgain(ctx context.Context, tx *domain.Tx, record func() *domain.AuditRecord) (
+	newBalanceFrom domain.Balance,
+	newBalanceTo domain.Balance,
+	err error,
+) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), u.ctxTimeout)
+	defer cancel()
+
+	err = u.inPrimaryTx(ctx, OpCreateTx, &sql.TxOptions{Isolation: sql.LevelReadCommitted},
+		func(ctx context.Context, dbTx *sql.Tx, rebalance bool) (err error) {
+			newBalanceFrom, newBalanceTo, err = u.createTx(ctx, dbTx, tx, rebalance)
+			if err != nil {
+				return err
+			}
+			return u.storeAudit(ctx, dbTx, record)
+		})
+	if !errors.Is(err, ErrDuplicateReference) {
+		return newBalanceFrom, newBalanceTo, err
+	}
+
+	err = u.inTx(ctx, OpCreateTx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true},
+		func(ctx context.Context, dbTx *sql.Tx) error {
+			existing, err := u.txRepo.GetByReference(ctx, dbTx, tx.From, tx.ExternalReference)
+			if err != nil {
+				return fmt.Errorf("get tx by reference: %w", err)
+			}
+			if existing.To != tx.To || existing.Value != tx.Value {
+				return fmt.Errorf("reference %q is taken by tx id=%s: %w",
+					tx.ExternalReference, existing.ID, ErrDuplicateReference)
+			}
+
+			newBalanceFrom, newBalanceTo = existing.FromBalance, existing.ToBalance
+			expect := tx.Expect
+			*tx = *existing
+			tx.Expect = expect
+			return nil
+		})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	u.logger.Ctx(ctx).Info("transfer found committed", "tx_id", tx.ID, "reference", tx.ExternalReference)
+	return newBalanceFrom, newBalanceTo, nil
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	limit := p.MaxDelay
+	if shift := attempt - 1; shift < 32 {
+		if d := p.BaseDelay << shift; d > 0 && d < limit {
+			limit = d
+		}
+	}
+
+	if limit <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(limit)))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,49 @@
+package general
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryable(t *testing.T) {
+	wrap := func(code string) error {
+		return fmt.Errorf("update user (from): %w", &pgconn.PgError{Code: code})
+	}
+
+	assert.True(t, IsRetryable(wrap(codeSerializationFailure)))
+	assert.True(t, IsRetryable(wrap(codeDeadlockDetected)))
+	assert.False(t, IsRetryable(wrap("23505")), "unique violation is not retryable")
+	assert.False(t, IsRetryable(ErrInsufficientBalance))
+	assert.False(t, IsRetryable(errors.New("conn closed")), "ambiguous errors must not be retried")
+	assert.False(t, IsRetryable(nil))
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	for i := 0; i < 100; i++ {
+		assert.Less(t, p.backoff(1), 10*time.Millisecond)
+		assert.Less(t, p.backoff(2), 20*time.Millisecond)
+		assert.Less(t, p.backoff(5), 50*time.Millisecond, "must be capped by MaxDelay")
+		assert.Less(t, p.backoff(100), 50*time.Millisecond, "must not overflow")
+		assert.GreaterOrEqual(t, p.backoff(3), time.Duration(0))
+	}
+
+	assert.Equal(t, time.Duration(0), RetryPolicy{}.backoff(1))
+}
+
+func TestCommitError(t *testing.T) {
+	pgErr := &pgconn.PgError{Code: codeSerializationFailure}
+	assert.Equal(t, pgErr, commitError(pgErr), "the DB has answered")
+	assert.Equal(t, sql.ErrTxDone, commitError(sql.ErrTxDone), "rolled back by database/sql")
+	assert.ErrorIs(t, commitError(context.DeadlineExceeded), ErrCommitUnknown, "the commit may be on the way")
+	assert.ErrorIs(t, commitError(errors.New("conn closed")), ErrCommitUnknown)
+	assert.NoError(t, commitError(nil))
+}
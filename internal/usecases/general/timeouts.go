@@ -0,0 +1,125 @@
+package general
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	sqlitedb "github.com/kaz-as/test-transactions/internal/sqlite"
+)
+
+// Postgres error codes of the timeouts set by Timeouts.
+const (
+	codeLockNotAvailable = "55P03"
+	codeQueryCanceled    = "57014"
+)
+
+var (
+	ErrLockTimeout      = errors.New("lock timeout")
+	ErrStatementTimeout = errors.New("statement timeout")
+)
+
+// DBTimeouts limit the waits within every DB transaction on Postgres, 0 disables a limit. Lock limits the wait
+// for a row lock, so a transfer stuck behind a hot account fails fast with ErrLockTimeout. Statement limits
+// every statement with ErrStatementTimeout; it is not set for the operations that are not limited
+// by the timeout of the use case, like taking snapshots.
+type DBTimeouts struct {
+	Lock      time.Duration
+	Statement time.Duration
+}
+
+// longOperations are run without the timeout of the use case.
+var longOperations = map[string]bool{
+	OpTakeSnapshots: true,
+	OpReconcile:     true,
+}
+
+// Timeouts sets the Postgres timeouts of every DB transaction. SQLite has a busy timeout of the connection instead,
+// see sqlite.Open.
+func Timeouts(t DBTimeouts) Option {
+	return func(u *UseCase) {
+		u.timeouts = t
+	}
+}
+
+// Isolation sets the isolation levels of the DB transactions by operation, overriding the ones of the use case.
+// The read-only operations stay read-only. The serialization failures of the stricter levels are retried.
+func Isolation(levels map[string]sql.IsolationLevel) Option {
+	return func(u *UseCase) {
+		u.isolation = levels
+	}
+}
+
+// Operations are all the operations the isolation level can be set for.
+var Operations = []string{
+	OpCreateUser,
+	OpCreateTx,
+	OpCreateTxBatch,
+	OpQuoteTx,
+	OpGetBalance,
+	OpGetUser,
+	OpTakeSnapshots,
+	OpHistory,
+	OpReconcile,
+	OpRecordAudit,
+	OpEventCursor,
+	OpBalanceEvents,
+}
+
+func (u *UseCase) txOptions(operation string, opts *sql.TxOptions) *sql.TxOptions {
+	level, ok := u.isolation[operation]
+	if !ok {
+		return opts
+	}
+
+	return &sql.TxOptions{Isolation: level, ReadOnly: opts.ReadOnly}
+}
+
+// setTimeouts sets the timeouts for the rest of the DB transaction.
+func (u *UseCase) setTimeouts(ctx context.Context, dbTx *sql.Tx, operation string) error {
+	lock, statement := u.timeouts.Lock, u.timeouts.Statement
+	if longOperations[operation] {
+		statement = 0
+	}
+	if lock <= 0 && statement <= 0 {
+		return nil
+	}
+
+	// the settings are local to the DB transaction; 0 is no limit for Postgres too
+	query := `SELECT set_config('lock_timeout', $1, true), set_config('statement_timeout', $2, true)`
+
+	_, err := dbTx.ExecContext(ctx, query,
+		strconv.FormatInt(lock.Milliseconds(), 10), strconv.FormatInt(statement.Milliseconds(), 10))
+	if err != nil {
+		return fmt.Errorf("set timeouts: %w", err)
+	}
+
+	return nil
+}
+
+// timeoutError tells the DB timeouts from the other failures of the DB transaction. A statement canceled
+// because of the context is not a statement timeout.
+func timeoutError(ctx context.Context, err error) error {
+	if sqlitedb.IsBusy(err) {
+		return fmt.Errorf("%w: %s", ErrLockTimeout, err)
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch {
+	case pgErr.Code == codeLockNotAvailable:
+		return fmt.Errorf("%w: %s", ErrLockTimeout, err)
+	case pgErr.Code == codeQueryCanceled && ctx.Err() == nil:
+		return fmt.Errorf("%w: %s", ErrStatementTimeout, err)
+	default:
+		return err
+	}
+}
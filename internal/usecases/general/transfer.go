@@ -0,0 +1,64 @@
+package general
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/policy"
+)
+
+// Transferrer is a transactions repository that applies a transfer in a single statement instead of the five
+// of the step-by-step path: locking both users, updating them and storing the transaction.
+type Transferrer interface {
+	// Transfer locks the users, neither of them primary, checks the balance and the precondition of the sender,
+	// the balance of the receiver and the external reference, moves the value and stores the transaction.
+	// It returns the users after the transfer, or sql.ErrNoRows, changing nothing, if any user is missing
+	// or any check fails.
+	Transfer(ctx context.Context, tx *sql.Tx, transaction *domain.Tx) (from, to *domain.User, err error)
+}
+
+// StepByStep makes the transfers run step by step even if the transactions repository is a Transferrer.
+func StepByStep() Option {
+	return func(u *UseCase) {
+		u.stepByStep = true
+	}
+}
+
+// transfer applies the transfer in a single statement if the repository is a Transferrer, neither user is primary
+// and the rest of the policy is up to the DB. ok is false if the transfer is left to the step-by-step path;
+// so is a rejected transfer, to get the error of the failed check.
+func (u *UseCase) transfer(ctx context.Context, dbTx *sql.Tx, tx *domain.Tx) (
+	newBalanceFrom domain.Balance,
+	newBalanceTo domain.Balance,
+	ok bool,
+	err error,
+) {
+	transferrer, isTransferrer := u.txRepo.(Transferrer)
+	if !isTransferrer || u.stepByStep || u.precheck == nil || tx.From == PrimaryUserID || tx.To == PrimaryUserID {
+		return 0, 0, false, nil
+	}
+
+	// a review or a deny is decided with the balances, as a later rule can deny a transfer sent to review
+	v := u.precheck.Evaluate(ctx, tx, &domain.User{ID: tx.From}, &domain.User{ID: tx.To})
+	if v.Decision != policy.Allow {
+		return 0, 0, false, nil
+	}
+
+	from, to, err := transferrer.Transfer(ctx, dbTx, tx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, false, nil
+	}
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("transfer: %w", err)
+	}
+
+	err = u.notifyTransfer(ctx, dbTx, tx, from.Balance, to.Balance)
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	return from.Balance, to.Balance, true, nil
+}
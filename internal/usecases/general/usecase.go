@@ -3,57 +3,196 @@ package general
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
+	"math/rand"
 	"strings"
 	"time"
 
 	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/policy"
+	"github.com/kaz-as/test-transactions/pkg/clock"
 	"github.com/kaz-as/test-transactions/pkg/logger"
+	"github.com/kaz-as/test-transactions/pkg/tracing"
 )
 
 const PrimaryUserID = "00000000000000000000000000000000"
 
+// DefaultPrimaryShards is the number of shards the migrations split the primary user balance into.
+const DefaultPrimaryShards = 16
+
+// Operation names used to report DB transactions.
+const (
+	OpCreateUser    = "createUser"
+	OpCreateTx      = "createTx"
+	OpCreateTxBatch = "createTxBatch"
+	OpQuoteTx       = "quoteTx"
+	OpGetBalance    = "getBalance"
+	OpGetUser       = "getUser"
+	OpTakeSnapshots = "takeSnapshots"
+	OpHistory       = "history"
+	OpReconcile     = "reconcile"
+	OpRecordAudit   = "recordAudit"
+	OpEventCursor   = "eventCursor"
+	OpBalanceEvents = "balanceEvents"
+)
+
+// TxObserver is notified about every finished DB transaction.
+type TxObserver interface {
+	// ObserveDBTx is called for every attempt of a DB transaction.
+	ObserveDBTx(operation string, duration time.Duration, committed bool)
+	// ObserveDBTxAttempts is called once the operation is done, retried or not.
+	ObserveDBTxAttempts(operation string, attempts int, committed bool)
+}
+
+type noopObserver struct{}
+
+func (noopObserver) ObserveDBTx(string, time.Duration, bool) {}
+
+func (noopObserver) ObserveDBTxAttempts(string, int, bool) {}
+
+type Option func(*UseCase)
+
+func Observer(o TxObserver) Option {
+	return func(u *UseCase) {
+		u.observer = o
+	}
+}
+
+// PrimaryShards sets the number of shards the balance of the primary user is spread over by rebalancing.
+// The shards missing in the DB are created by the first rebalancing.
+func PrimaryShards(n int) Option {
+	return func(u *UseCase) {
+		if n > 0 {
+			u.primaryShards = n
+		}
+	}
+}
+
+// Policy sets the rules the transfers are checked with, policy.Default() if not set.
+func Policy(e *policy.Engine) Option {
+	return func(u *UseCase) {
+		u.policy = e
+	}
+}
+
+// Reviewed lets the transfers the policy sends to review proceed: the caller has reviewed them,
+// e.g. an operator of the admin tool.
+func Reviewed() Option {
+	return func(u *UseCase) {
+		u.reviewed = true
+	}
+}
+
+// Retry sets the policy of retrying DB transactions aborted by a serialization failure or a deadlock.
+func Retry(p RetryPolicy) Option {
+	return func(u *UseCase) {
+		u.retry = p
+	}
+}
+
+// Clock sets the clock of the use case, the one the transactions are stamped with; clock.System() if not set.
+func Clock(c clock.Clock) Option {
+	return func(u *UseCase) {
+		u.clock = c
+	}
+}
+
 type UseCase struct {
-	logger     logger.Interface
-	db         *sql.DB
-	usersRepo  domain.UsersRepository
-	txRepo     domain.TxRepository
-	ctxTimeout time.Duration
+	logger        logger.Interface
+	db            *sql.DB
+	usersRepo     domain.UsersRepository
+	shardsRepo    domain.PrimaryShardsRepository
+	txRepo        domain.TxRepository
+	snapshotsRepo domain.SnapshotsRepository
+	auditRepo     domain.AuditRepository
+	eventsRepo    domain.EventsRepository
+	ctxTimeout    time.Duration
+	observer      TxObserver
+	retry         RetryPolicy
+	primaryShards int
+	policy        *policy.Engine
+	reviewed      bool
+	isolation     map[string]sql.IsolationLevel
+	timeouts      DBTimeouts
+	clock         clock.Clock
+	// precheck is the part of the policy checked before a single-statement transfer, nil if it cannot be run
+	precheck   *policy.Engine
+	stepByStep bool
 }
 
 func NewUseCase(
 	log logger.Interface,
 	db *sql.DB,
 	usersRepo domain.UsersRepository,
+	shardsRepo domain.PrimaryShardsRepository,
 	txRepo domain.TxRepository,
+	snapshotsRepo domain.SnapshotsRepository,
+	auditRepo domain.AuditRepository,
+	eventsRepo domain.EventsRepository,
 	ctxTimeout time.Duration,
+	opts ...Option,
 ) *UseCase {
-	return &UseCase{
-		logger:     log,
-		db:         db,
-		usersRepo:  usersRepo,
-		txRepo:     txRepo,
-		ctxTimeout: ctxTimeout,
+	u := &UseCase{
+		logger:        log,
+		db:            db,
+		usersRepo:     usersRepo,
+		shardsRepo:    shardsRepo,
+		txRepo:        txRepo,
+		snapshotsRepo: snapshotsRepo,
+		auditRepo:     auditRepo,
+		eventsRepo:    eventsRepo,
+		ctxTimeout:    ctxTimeout,
+		observer:      noopObserver{},
+		retry:         DefaultRetryPolicy,
+		primaryShards: DefaultPrimaryShards,
+		policy:        policy.Default(),
+		clock:         clock.System(),
 	}
+
+	for _, opt := range opts {
+		opt(u)
+	}
+
+	if precheck, ok := u.policy.WithoutBalances(); ok {
+		u.precheck = precheck
+	}
+
+	return u
 }
 
 func (u *UseCase) CreateUser(ctx context.Context, user *domain.User) error {
+	return u.CreateUserAudited(ctx, user, nil)
+}
+
+// CreateUserAudited is CreateUser storing the audit record in the same DB transaction, so the user is never
+// created unaudited. record is called once the user is created, with its id set; nil records nothing.
+func (u *UseCase) CreateUserAudited(ctx context.Context, user *domain.User, record func() *domain.AuditRecord) error {
 	ctxTimeout, cancel := context.WithTimeout(ctx, u.ctxTimeout)
 	defer cancel()
 
-	dbTx, err := u.db.BeginTx(ctxTimeout, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
-	if err != nil {
-		return fmt.Errorf("begin tx: %w", err)
-	}
-	defer u.rollback(dbTx)
+	return u.inPrimaryTx(ctxTimeout, OpCreateUser, &sql.TxOptions{Isolation: sql.LevelReadCommitted},
+		func(ctx context.Context, dbTx *sql.Tx, rebalance bool) error {
+			err := u.createUser(ctx, dbTx, user, rebalance)
+			if err != nil {
+				return err
+			}
+			return u.storeAudit(ctx, dbTx, record)
+		})
+}
 
-	primaryUser, err := u.usersRepo.GetForUpdate(ctxTimeout, dbTx, PrimaryUserID)
+func (u *UseCase) createUser(ctxTimeout context.Context, dbTx *sql.Tx, user *domain.User, rebalance bool) error {
+	shard, err := u.lockPrimary(ctxTimeout, dbTx, user.Balance, rebalance)
 	if err != nil {
-		return fmt.Errorf("get primary user: %w", err)
+		return fmt.Errorf("lock primary user: %w", err)
 	}
 
+	primaryUser := &domain.User{ID: PrimaryUserID, Balance: shard.Balance}
+
+	// the user is stored with the balance after its first transaction
+	user.Seq = 1
+
 	err = u.usersRepo.Store(ctxTimeout, dbTx, user)
 	if err != nil {
 		return fmt.Errorf("storing user: %w", err)
@@ -67,119 +206,882 @@ func (u *UseCase) CreateUser(ctx context.Context, user *domain.User) error {
 	}()
 
 	businessTx := &domain.Tx{
-		From:  PrimaryUserID,
-		To:    user.ID,
-		Value: newUserBalance,
+		From:      PrimaryUserID,
+		To:        user.ID,
+		Value:     newUserBalance,
+		ToSeq:     user.Seq,
+		ToBalance: newUserBalance,
 	}
 
-	if err = u.checkBusinessTx(businessTx, primaryUser, user); err != nil {
+	if err = u.checkPolicy(ctxTimeout, businessTx, primaryUser, user); err != nil {
 		return fmt.Errorf("check failed: %w", err)
 	}
 
 	primaryUser.Balance -= businessTx.Value
 
-	err = u.txRepo.Store(ctxTimeout, dbTx, businessTx)
+	err = u.update(ctxTimeout, dbTx, primaryUser, shard)
 	if err != nil {
-		return fmt.Errorf("storing first tx for the user: %w", err)
+		return fmt.Errorf("update primary user: %w", err)
 	}
 
-	err = u.usersRepo.Update(ctxTimeout, dbTx, primaryUser)
+	err = u.postPrimary(ctxTimeout, dbTx, businessTx)
 	if err != nil {
-		return fmt.Errorf("update primary user: %w", err)
+		return err
 	}
 
-	return dbTx.Commit()
+	err = u.txRepo.Store(ctxTimeout, dbTx, businessTx)
+	if err != nil {
+		return fmt.Errorf("storing first tx for the user: %w", err)
+	}
+
+	return u.notifyTransfer(ctxTimeout, dbTx, businessTx, businessTx.FromBalance, newUserBalance)
 }
 
+// CreateTx moves the value between the users. If its commit gets no answer, a transfer with an external reference
+// is run again and stored at most once, see createTxAgain; the one without fails with ErrCommitUnknown.
 func (u *UseCase) CreateTx(ctx context.Context, tx *domain.Tx) (
 	newBalanceFrom domain.Balance,
 	newBalanceTo domain.Balance,
 	err error,
+) {
+	return u.CreateTxAudited(ctx, tx, nil)
+}
+
+// CreateTxAudited is CreateTx storing the audit record in the DB transaction of the transfer, so the transfer
+// is never applied unaudited. record is called once the transfer is applied, with its id set; nil records nothing.
+func (u *UseCase) CreateTxAudited(ctx context.Context, tx *domain.Tx, record func() *domain.AuditRecord) (
+	newBalanceFrom domain.Balance,
+	newBalanceTo domain.Balance,
+	err error,
 ) {
 	ctxTimeout, cancel := context.WithTimeout(ctx, u.ctxTimeout)
 	defer cancel()
 
-	dbTx, err := u.db.BeginTx(ctxTimeout, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	err = u.inPrimaryTx(ctxTimeout, OpCreateTx, &sql.TxOptions{Isolation: sql.LevelReadCommitted},
+		func(ctx context.Context, dbTx *sql.Tx, rebalance bool) (err error) {
+			newBalanceFrom, newBalanceTo, err = u.createTx(ctx, dbTx, tx, rebalance)
+			if err != nil {
+				return err
+			}
+			return u.storeAudit(ctx, dbTx, record)
+		})
+	if errors.Is(err, ErrCommitUnknown) && tx.ExternalReference != "" {
+		u.logger.Ctx(ctx).Warn("transfer commit got no answer, running it again",
+			"reference", tx.ExternalReference, "error", err)
+		newBalanceFrom, newBalanceTo, err = u.createTxAgain(ctx, tx, record)
+	}
 	if err != nil {
-		return 0, 0, fmt.Errorf("begin tx: %w", err)
+		return 0, 0, err
+	}
+
+	return newBalanceFrom, newBalanceTo, nil
+}
+
+var errQuoted = errors.New("quoted transfer rolled back")
+
+// QuoteTx runs the transfer with its locks and checks in a DB transaction that is always rolled back,
+// so it neither persists nor publishes anything. The policy violations are returned in the quote, not as an error.
+func (u *UseCase) QuoteTx(ctx context.Context, tx *domain.Tx) (quote domain.Quote, err error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, u.ctxTimeout)
+	defer cancel()
+
+	err = u.inPrimaryTx(ctxTimeout, OpQuoteTx, &sql.TxOptions{Isolation: sql.LevelReadCommitted},
+		func(ctx context.Context, dbTx *sql.Tx, rebalance bool) error {
+			// the id and the timestamp set by the repository must not leak to the caller
+			quoted := *tx
+			quote = domain.Quote{}
+
+			newBalanceFrom, newBalanceTo, err := u.createTx(ctx, dbTx, &quoted, rebalance)
+
+			var violation *ViolationError
+			if errors.As(err, &violation) {
+				for _, v := range violation.Violations {
+					quote.Violations = append(quote.Violations, domain.Violation{
+						Decision: string(v.Decision),
+						Rule:     v.Rule,
+						Reason:   v.Reason,
+						Message:  verdictError(v).Error(),
+					})
+				}
+				return errQuoted
+			}
+			if err != nil {
+				return err
+			}
+
+			quote.NewBalanceFrom = newBalanceFrom
+			quote.NewBalanceTo = newBalanceTo
+			return errQuoted
+		})
+	if !errors.Is(err, errQuoted) {
+		return domain.Quote{}, err
 	}
-	defer u.rollback(dbTx)
 
-	var from, to *domain.User
+	return quote, nil
+}
 
-	// avoid deadlock
+func (u *UseCase) createTx(ctxTimeout context.Context, dbTx *sql.Tx, tx *domain.Tx, rebalance bool) (
+	newBalanceFrom domain.Balance,
+	newBalanceTo domain.Balance,
+	err error,
+) {
+	if err = checkDetails(tx); err != nil {
+		return 0, 0, fmt.Errorf("check failed: %w", err)
+	}
+
+	newBalanceFrom, newBalanceTo, ok, err := u.transfer(ctxTimeout, dbTx, tx)
+	if err != nil || ok {
+		return newBalanceFrom, newBalanceTo, err
+	}
+
+	var (
+		from, to           *domain.User
+		fromShard, toShard *domain.PrimaryShard
+	)
+
+	// avoid deadlock; the primary user is the first, so its shards are always locked before the users
 	if strings.Compare(string(tx.From), string(tx.To)) < 1 {
-		from, err = u.usersRepo.GetForUpdate(ctxTimeout, dbTx, tx.From)
+		from, fromShard, err = u.lockUser(ctxTimeout, dbTx, tx.From, tx.Value, rebalance)
 		if err != nil {
 			return 0, 0, fmt.Errorf("get user (from) for update: %w", err)
 		}
-		to, err = u.usersRepo.GetForUpdate(ctxTimeout, dbTx, tx.To)
+		to, toShard, err = u.lockUser(ctxTimeout, dbTx, tx.To, 0, rebalance)
 		if err != nil {
 			return 0, 0, fmt.Errorf("get user (to) for update: %w", err)
 		}
 	} else {
-		to, err = u.usersRepo.GetForUpdate(ctxTimeout, dbTx, tx.To)
+		to, toShard, err = u.lockUser(ctxTimeout, dbTx, tx.To, 0, rebalance)
 		if err != nil {
 			return 0, 0, fmt.Errorf("get user (to) for update: %w", err)
 		}
-		from, err = u.usersRepo.GetForUpdate(ctxTimeout, dbTx, tx.From)
+		from, fromShard, err = u.lockUser(ctxTimeout, dbTx, tx.From, tx.Value, rebalance)
 		if err != nil {
 			return 0, 0, fmt.Errorf("get user (from) for update: %w", err)
 		}
 	}
 
-	if err = u.checkBusinessTx(tx, from, to); err != nil {
+	if err = checkPrecondition(tx, from); err != nil {
 		return 0, 0, fmt.Errorf("check failed: %w", err)
 	}
 
-	err = u.txRepo.Store(ctxTimeout, dbTx, tx)
-	if err != nil {
-		return 0, 0, fmt.Errorf("transaction storing: %w", err)
+	if err = u.checkPolicy(ctxTimeout, tx, from, to); err != nil {
+		return 0, 0, fmt.Errorf("check failed: %w", err)
+	}
+
+	if err = u.checkReference(ctxTimeout, dbTx, tx); err != nil {
+		return 0, 0, err
 	}
 
 	from.Balance -= tx.Value
 	to.Balance += tx.Value
+	post(tx, from, to)
 
-	err = u.usersRepo.Update(ctxTimeout, dbTx, from)
+	err = u.update(ctxTimeout, dbTx, from, fromShard)
 	if err != nil {
 		return 0, 0, fmt.Errorf("update user (from): %w", err)
 	}
 
-	err = u.usersRepo.Update(ctxTimeout, dbTx, to)
+	err = u.update(ctxTimeout, dbTx, to, toShard)
 	if err != nil {
 		return 0, 0, fmt.Errorf("update user (to): %w", err)
 	}
 
-	return from.Balance, to.Balance, dbTx.Commit()
+	if fromShard != nil || toShard != nil {
+		err = u.postPrimary(ctxTimeout, dbTx, tx)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	err = u.txRepo.Store(ctxTimeout, dbTx, tx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("transaction storing: %w", err)
+	}
+
+	newBalanceFrom, newBalanceTo = tx.FromBalance, tx.ToBalance
+
+	err = u.notifyTransfer(ctxTimeout, dbTx, tx, newBalanceFrom, newBalanceTo)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return newBalanceFrom, newBalanceTo, nil
+}
+
+// notifyTransfer publishes the debit and the credit made by the stored transaction. It must be called
+// in the DB transaction of the transfer, so the events are delivered if and only if it is committed.
+func (u *UseCase) notifyTransfer(
+	ctx context.Context,
+	dbTx *sql.Tx,
+	tx *domain.Tx,
+	newBalanceFrom, newBalanceTo domain.Balance,
+) error {
+	err := u.eventsRepo.Notify(ctx, dbTx,
+		domain.BalanceEvent{
+			TxID:      tx.ID,
+			UserID:    tx.From,
+			Seq:       tx.FromSeq,
+			Delta:     -tx.Value,
+			Balance:   newBalanceFrom,
+			Timestamp: tx.Timestamp,
+		},
+		domain.BalanceEvent{
+			TxID:      tx.ID,
+			UserID:    tx.To,
+			Seq:       tx.ToSeq,
+			Delta:     tx.Value,
+			Balance:   newBalanceTo,
+			Timestamp: tx.Timestamp,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("notify balance events: %w", err)
+	}
+
+	return nil
+}
+
+// GetUser returns the current balance of the user with its version; the primary user has the sum of the shards.
+func (u *UseCase) GetUser(ctx context.Context, userID domain.UserID) (user *domain.User, err error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, u.ctxTimeout)
+	defer cancel()
+
+	err = u.inTx(ctxTimeout, OpGetUser, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true},
+		func(ctx context.Context, dbTx *sql.Tx) (err error) {
+			user, err = u.usersRepo.Get(ctx, dbTx, userID)
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("user id=%s: %w", userID, ErrUserNotFound)
+			}
+			if err != nil {
+				return fmt.Errorf("get user: %w", err)
+			}
+
+			if userID == PrimaryUserID {
+				user.Balance, err = u.shardsRepo.Total(ctx, dbTx)
+				if err != nil {
+					return fmt.Errorf("primary shards total: %w", err)
+				}
+			}
+
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (u *UseCase) GetBalance(ctx context.Context, userID domain.UserID, at *time.Time) (balance domain.Balance, err error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, u.ctxTimeout)
+	defer cancel()
+
+	err = u.inTx(ctxTimeout, OpGetBalance, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true},
+		func(ctx context.Context, dbTx *sql.Tx) (err error) {
+			balance, err = u.getBalance(ctx, dbTx, userID, at)
+			return err
+		})
+	if err != nil {
+		return 0, err
+	}
+
+	return balance, nil
+}
+
+func (u *UseCase) getBalance(ctxTimeout context.Context, dbTx *sql.Tx, userID domain.UserID, at *time.Time) (
+	domain.Balance,
+	error,
+) {
+	user, err := u.usersRepo.Get(ctxTimeout, dbTx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("user id=%s: %w", userID, ErrUserNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get user: %w", err)
+	}
+
+	if at == nil && userID == PrimaryUserID {
+		balance, err := u.shardsRepo.Total(ctxTimeout, dbTx)
+		if err != nil {
+			return 0, fmt.Errorf("primary shards total: %w", err)
+		}
+		return balance, nil
+	}
+
+	if at == nil {
+		return user.Balance, nil
+	}
+
+	balance, err := u.snapshotsRepo.BalanceAt(ctxTimeout, dbTx, userID, *at)
+	if err != nil {
+		return 0, fmt.Errorf("balance at %s: %w", at.Format(time.RFC3339Nano), err)
+	}
+
+	return balance, nil
+}
+
+// Now returns the time of the clock of the use case.
+func (u *UseCase) Now() time.Time {
+	return u.clock.Now()
+}
+
+// TakeSnapshots stores balances as of upTo. All the transactions with earlier timestamps must be already committed
+// or rolled back, so upTo should lag behind the current time more than any DB transaction can last.
+func (u *UseCase) TakeSnapshots(ctx context.Context, upTo time.Time) (taken int64, err error) {
+	err = u.inTx(ctx, OpTakeSnapshots, &sql.TxOptions{Isolation: sql.LevelReadCommitted},
+		func(ctx context.Context, dbTx *sql.Tx) (err error) {
+			taken, err = u.snapshotsRepo.Take(ctx, dbTx, upTo)
+			if err != nil {
+				return fmt.Errorf("take snapshots: %w", err)
+			}
+			return nil
+		})
+	if err != nil {
+		return 0, err
+	}
+
+	return taken, nil
+}
+
+// History returns up to limit latest transactions of the user matching the filter, the newest first.
+func (u *UseCase) History(ctx context.Context, userID domain.UserID, filter domain.TxFilter, limit int) (
+	txs []domain.Tx,
+	err error,
+) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, u.ctxTimeout)
+	defer cancel()
+
+	err = u.inTx(ctxTimeout, OpHistory, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true},
+		func(ctx context.Context, dbTx *sql.Tx) error {
+			_, err := u.usersRepo.Get(ctx, dbTx, userID)
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("user id=%s: %w", userID, ErrUserNotFound)
+			}
+			if err != nil {
+				return fmt.Errorf("get user: %w", err)
+			}
+
+			txs, err = u.txRepo.ListByUser(ctx, dbTx, userID, filter, limit)
+			if err != nil {
+				return fmt.Errorf("list transactions: %w", err)
+			}
+
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return txs, nil
+}
+
+// Reconcile returns the users whose balances do not match their transactions. It scans all the transactions,
+// so it is not limited by the use case timeout, only by ctx.
+func (u *UseCase) Reconcile(ctx context.Context) (discrepancies []domain.Discrepancy, err error) {
+	err = u.inTx(ctx, OpReconcile, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true},
+		func(ctx context.Context, dbTx *sql.Tx) (err error) {
+			discrepancies, err = u.usersRepo.Discrepancies(ctx, dbTx)
+			if err != nil {
+				return fmt.Errorf("discrepancies: %w", err)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return discrepancies, nil
+}
+
+// EventCursor returns the position of the transaction in the balance events of the user.
+func (u *UseCase) EventCursor(ctx context.Context, userID domain.UserID, txID string) (
+	cursor domain.EventCursor,
+	err error,
+) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, u.ctxTimeout)
+	defer cancel()
+
+	err = u.inTx(ctxTimeout, OpEventCursor, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: true},
+		func(ctx context.Context, dbTx *sql.Tx) (err error) {
+			cursor, err = u.eventsRepo.Cursor(ctx, dbTx, userID, txID)
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("tx id=%s of user id=%s: %w", txID, userID, ErrEventNotFound)
+			}
+			if err != nil {
+				return fmt.Errorf("event cursor: %w", err)
+			}
+			return nil
+		})
+
+	return cursor, err
+}
+
+// BalanceEvents returns up to limit balance events of the user after the cursor, the oldest first.
+func (u *UseCase) BalanceEvents(ctx context.Context, userID domain.UserID, after domain.EventCursor, limit int) (
+	events []domain.BalanceEvent,
+	err error,
+) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, u.ctxTimeout)
+	defer cancel()
+
+	err = u.inTx(ctxTimeout, OpBalanceEvents, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true},
+		func(ctx context.Context, dbTx *sql.Tx) (err error) {
+			events, err = u.eventsRepo.ListAfter(ctx, dbTx, userID, after, limit)
+			if err != nil {
+				return fmt.Errorf("list events: %w", err)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// RecordAudit stores the audit record of an action that has changed nothing, e.g. a read or a failed one;
+// a change is audited in its DB transaction, see CreateTxAudited.
+func (u *UseCase) RecordAudit(ctx context.Context, record *domain.AuditRecord) error {
+	ctxTimeout, cancel := context.WithTimeout(ctx, u.ctxTimeout)
+	defer cancel()
+
+	return u.inTx(ctxTimeout, OpRecordAudit, &sql.TxOptions{Isolation: sql.LevelReadCommitted},
+		func(ctx context.Context, dbTx *sql.Tx) error {
+			return u.storeAudit(ctx, dbTx, func() *domain.AuditRecord { return record })
+		})
+}
+
+// storeAudit stores the record returned by record, if it is not nil.
+func (u *UseCase) storeAudit(ctx context.Context, dbTx *sql.Tx, record func() *domain.AuditRecord) error {
+	if record == nil {
+		return nil
+	}
+
+	err := u.auditRepo.Store(ctx, dbTx, record())
+	if err != nil {
+		return fmt.Errorf("store audit record: %w", err)
+	}
+	return nil
 }
 
 var (
+	ErrUserNotFound        = errors.New("user not found")
+	ErrEventNotFound       = errors.New("event not found")
 	ErrSame                = errors.New("to = from")
 	ErrNegativeTx          = errors.New("negative tx")
 	ErrInsufficientBalance = errors.New("insufficient balance")
 	ErrTooMuch             = errors.New("too much")
+	ErrInvalidDetails      = errors.New("invalid tx details")
+	ErrDuplicateReference  = domain.ErrDuplicateReference
+	ErrDenied              = errors.New("denied by policy")
+	ErrPreconditionFailed  = errors.New("precondition failed")
+	ErrReviewRequired      = errors.New("review required")
 )
 
-func (u *UseCase) checkBusinessTx(tx *domain.Tx, from *domain.User, to *domain.User) error {
-	if from.ID == to.ID {
-		return fmt.Errorf("user id=%s: %w", from.ID, ErrSame)
+// denials are the errors of the built-in rules by their reason codes.
+var denials = map[string]error{
+	policy.ReasonSameAccount:         ErrSame,
+	policy.ReasonNegativeValue:       ErrNegativeTx,
+	policy.ReasonInsufficientBalance: ErrInsufficientBalance,
+	policy.ReasonTooMuch:             ErrTooMuch,
+}
+
+// Limits of the details the client can attach to a transaction.
+const (
+	MaxDescriptionLength       = 1024
+	MaxExternalReferenceLength = 128
+	// MaxMetadataSize is the size of the metadata encoded as JSON.
+	MaxMetadataSize = 4096
+)
+
+// getForUpdate locks the user, so its balance cannot be changed by other DB transactions.
+func (u *UseCase) getForUpdate(ctx context.Context, dbTx *sql.Tx, userID domain.UserID) (*domain.User, error) {
+	user, err := u.usersRepo.GetForUpdate(ctx, dbTx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("user id=%s: %w", userID, ErrUserNotFound)
+	}
+
+	return user, err
+}
+
+var errShardDry = errors.New("primary shard has not enough money")
+
+// lockUser locks the user, or a shard of the primary user with at least debit on it.
+// The primary user is returned with the balance of the shard.
+func (u *UseCase) lockUser(
+	ctx context.Context,
+	dbTx *sql.Tx,
+	userID domain.UserID,
+	debit domain.Balance,
+	rebalance bool,
+) (*domain.User, *domain.PrimaryShard, error) {
+	if userID != PrimaryUserID {
+		user, err := u.getForUpdate(ctx, dbTx, userID)
+		return user, nil, err
+	}
+
+	shard, err := u.lockPrimary(ctx, dbTx, debit, rebalance)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &domain.User{ID: PrimaryUserID, Balance: shard.Balance}, shard, nil
+}
+
+// lockPrimary locks a random shard of the primary user, so concurrent operations rarely wait for each other.
+// If the shard has less than debit, errShardDry makes the operation run again with rebalance.
+func (u *UseCase) lockPrimary(ctx context.Context, dbTx *sql.Tx, debit domain.Balance, rebalance bool) (
+	*domain.PrimaryShard,
+	error,
+) {
+	if rebalance {
+		return u.rebalance(ctx, dbTx, debit)
+	}
+
+	shard, err := u.shardsRepo.GetForUpdate(ctx, dbTx, rand.Intn(u.primaryShards))
+	if errors.Is(err, sql.ErrNoRows) {
+		// the missing shards are created by rebalancing
+		return nil, errShardDry
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get primary shard for update: %w", err)
+	}
+
+	if shard.Balance < debit {
+		return nil, errShardDry
+	}
+
+	return shard, nil
+}
+
+// rebalance waits for the other rebalances, locks all the shards in order and spreads the primary user balance evenly over the configured number
+// of them, shard 0 getting reserve on top of its share; the shards beyond the number are emptied.
+// Shard 0 is returned. If the whole balance is less than reserve, nothing is moved, so the operation fails its check.
+func (u *UseCase) rebalance(ctx context.Context, dbTx *sql.Tx, reserve domain.Balance) (*domain.PrimaryShard, error) {
+	shards, err := u.shardsRepo.ListForUpdate(ctx, dbTx)
+	if err != nil {
+		return nil, fmt.Errorf("list primary shards for update: %w", err)
+	}
+
+	var total domain.Balance
+	current := make(map[int]domain.Balance, len(shards))
+	for _, shard := range shards {
+		total += shard.Balance
+		current[shard.ID] = shard.Balance
+	}
+
+	if total < reserve {
+		return &domain.PrimaryShard{ID: 0, Balance: total}, nil
+	}
+
+	n := domain.Balance(u.primaryShards)
+	share := (total - reserve) / n
+
+	target := make(map[int]domain.Balance, len(shards)+u.primaryShards)
+	for _, shard := range shards {
+		target[shard.ID] = 0
+	}
+	for id := 0; id < u.primaryShards; id++ {
+		target[id] = share
+	}
+	target[0] += reserve + (total-reserve)%n
+
+	for id, balance := range target {
+		if b, ok := current[id]; ok && b == balance {
+			continue
+		}
+
+		err = u.shardsRepo.Save(ctx, dbTx, &domain.PrimaryShard{ID: id, Balance: balance})
+		if err != nil {
+			return nil, fmt.Errorf("save primary shard: %w", err)
+		}
+	}
+
+	u.logger.Ctx(ctx).Info("primary shards rebalanced", "shards", u.primaryShards, "reserve", reserve)
+
+	return &domain.PrimaryShard{ID: 0, Balance: target[0]}, nil
+}
+
+// update stores the balance of the user, or of the shard the primary user is locked by.
+func (u *UseCase) update(ctx context.Context, dbTx *sql.Tx, user *domain.User, shard *domain.PrimaryShard) error {
+	if shard == nil {
+		return u.usersRepo.Update(ctx, dbTx, user)
+	}
+
+	shard.Balance = user.Balance
+	return u.shardsRepo.Save(ctx, dbTx, shard)
+}
+
+// checkPrecondition compares the locked sender with the state the client expects it to be in.
+func checkPrecondition(tx *domain.Tx, from *domain.User) error {
+	expect := tx.Expect
+	if expect.Version == nil && expect.Balance == nil {
+		return nil
+	}
+
+	if from.ID == PrimaryUserID {
+		return fmt.Errorf("user id=%s is not versioned: %w", from.ID, ErrPreconditionFailed)
+	}
+	if expect.Version != nil && *expect.Version != from.Version {
+		return fmt.Errorf("user id=%s version=%d, expected %d: %w", from.ID, from.Version, *expect.Version,
+			ErrPreconditionFailed)
+	}
+	if expect.Balance != nil && *expect.Balance != from.Balance {
+		return fmt.Errorf("user id=%s balance=%d, expected %d: %w", from.ID, from.Balance, *expect.Balance,
+			ErrPreconditionFailed)
+	}
+
+	return nil
+}
+
+// post numbers the transaction among the ones of the locked users and records their balances after it,
+// so the numbers have no gaps. The primary user is locked by a shard only, so it is numbered by postPrimary.
+func post(tx *domain.Tx, from, to *domain.User) {
+	if from.ID != PrimaryUserID {
+		from.Seq++
+		tx.FromSeq, tx.FromBalance = from.Seq, from.Balance
+	}
+	if to.ID != PrimaryUserID {
+		to.Seq++
+		tx.ToSeq, tx.ToBalance = to.Seq, to.Balance
+	}
+}
+
+// postPrimary numbers the transaction among the ones of the primary user and records its balance after it.
+// The shard is locked alone, so the numbers are taken under a lock of their own, the users row of the primary
+// user: it is locked last, after the shard is updated, so the primary operations wait for each other only
+// to commit. The holders before have committed by then, so the sum of the shards is the balance after.
+func (u *UseCase) postPrimary(ctx context.Context, dbTx *sql.Tx, tx *domain.Tx) error {
+	seq, err := u.usersRepo.NextSeq(ctx, dbTx, PrimaryUserID)
+	if err != nil {
+		return fmt.Errorf("number primary user tx: %w", err)
 	}
-	if tx.Value < 0 {
-		return fmt.Errorf("value=%d: %w", tx.Value, ErrNegativeTx)
+
+	balance, err := u.shardsRepo.Total(ctx, dbTx)
+	if err != nil {
+		return fmt.Errorf("primary user balance: %w", err)
 	}
-	if from.Balance < tx.Value {
-		return fmt.Errorf("user id=%s: %w", from.ID, ErrInsufficientBalance)
+
+	if tx.From == PrimaryUserID {
+		tx.FromSeq, tx.FromBalance = seq, balance
+	} else {
+		tx.ToSeq, tx.ToBalance = seq, balance
+	}
+
+	return nil
+}
+
+// ViolationError is the verdict of the policy that stopped a transfer. It wraps the error of the verdict:
+// the denials of the built-in rules keep their own errors, the other ones are ErrDenied or ErrReviewRequired.
+type ViolationError struct {
+	Verdict policy.Verdict
+	// Violations are the deny and review verdicts of all the rules, Verdict among them.
+	Violations []policy.Verdict
+	err        error
+}
+
+func (e *ViolationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ViolationError) Unwrap() error {
+	return e.err
+}
+
+func (u *UseCase) checkPolicy(ctx context.Context, tx *domain.Tx, from *domain.User, to *domain.User) error {
+	v := u.policy.Evaluate(ctx, tx, from, to)
+
+	switch v.Decision {
+	case policy.Allow:
+		return nil
+	case policy.Review:
+		if u.reviewed {
+			u.logger.Ctx(ctx).Info("reviewed transfer proceeds", "rule", v.Rule, "reason", v.Reason)
+			return nil
+		}
 	}
-	if math.MaxInt64-tx.Value < to.Balance {
-		return fmt.Errorf("user id=%s: %w", to.ID, ErrTooMuch)
+
+	// the rules are checked again for the violations after the stopping one, as Evaluate returns at a deny
+	return &ViolationError{Verdict: v, Violations: u.policy.Violations(ctx, tx, from, to), err: verdictError(v)}
+}
+
+// verdictError is the error of a deny or review verdict.
+func verdictError(v policy.Verdict) error {
+	if v.Decision == policy.Review {
+		return fmt.Errorf("rule %s: %s: %s: %w", v.Rule, v.Reason, v.Message, ErrReviewRequired)
+	}
+
+	if err, ok := denials[v.Reason]; ok {
+		return fmt.Errorf("%s: %w", v.Message, err)
+	}
+
+	return fmt.Errorf("rule %s: %s: %s: %w", v.Rule, v.Reason, v.Message, ErrDenied)
+}
+
+func checkDetails(tx *domain.Tx) error {
+	if len(tx.Description) > MaxDescriptionLength {
+		return fmt.Errorf("description longer than %d: %w", MaxDescriptionLength, ErrInvalidDetails)
+	}
+	if len(tx.ExternalReference) > MaxExternalReferenceLength {
+		return fmt.Errorf("external reference longer than %d: %w", MaxExternalReferenceLength, ErrInvalidDetails)
+	}
+
+	if len(tx.Metadata) == 0 {
+		return nil
+	}
+
+	b, err := json.Marshal(tx.Metadata)
+	if err != nil {
+		return fmt.Errorf("metadata: %s: %w", err, ErrInvalidDetails)
+	}
+	if len(b) > MaxMetadataSize {
+		return fmt.Errorf("metadata larger than %d bytes: %w", MaxMetadataSize, ErrInvalidDetails)
 	}
 
 	return nil
 }
 
-func (u *UseCase) rollback(tx *sql.Tx) {
+// checkReference rejects a transaction repeating the external reference of the sender. The sender is locked,
+// so no concurrent transaction can take the reference; the transactions of the primary user lock only a shard,
+// for them the unique index is the last resort.
+func (u *UseCase) checkReference(ctx context.Context, dbTx *sql.Tx, tx *domain.Tx) error {
+	if tx.ExternalReference == "" {
+		return nil
+	}
+
+	existing, err := u.txRepo.GetByReference(ctx, dbTx, tx.From, tx.ExternalReference)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get tx by reference: %w", err)
+	}
+
+	return fmt.Errorf("reference %q is taken by tx id=%s: %w", tx.ExternalReference, existing.ID, ErrDuplicateReference)
+}
+
+// inPrimaryTx runs fn like inTx. If a shard of the primary user has not enough money, fn is run again
+// in a new DB transaction with rebalance, locking all the shards.
+func (u *UseCase) inPrimaryTx(
+	ctx context.Context,
+	operation string,
+	opts *sql.TxOptions,
+	fn func(ctx context.Context, dbTx *sql.Tx, rebalance bool) error,
+) error {
+	err := u.inTx(ctx, operation, opts, func(ctx context.Context, dbTx *sql.Tx) error {
+		return fn(ctx, dbTx, false)
+	})
+	if !errors.Is(err, errShardDry) {
+		return err
+	}
+
+	return u.inTx(ctx, operation, opts, func(ctx context.Context, dbTx *sql.Tx) error {
+		return fn(ctx, dbTx, true)
+	})
+}
+
+// inTx runs fn in a DB transaction and commits it. The transaction is run again according to the retry policy
+// if Postgres aborts it as a serialization failure or a deadlock: such a transaction is guaranteed to be rolled back
+// as a whole, so nothing of the failed attempt persists. fn must not rely on the state left in memory
+// by a failed attempt, e.g. on the ids set by the repositories. The DB timeouts are not retried: they are
+// returned as ErrLockTimeout or ErrStatementTimeout.
+func (u *UseCase) inTx(
+	ctx context.Context,
+	operation string,
+	opts *sql.TxOptions,
+	fn func(ctx context.Context, dbTx *sql.Tx) error,
+) (err error) {
+	log := u.logger.Ctx(ctx)
+
+	attempt := 1
+	defer func() {
+		if !errors.Is(err, errQuoted) {
+			u.observer.ObserveDBTxAttempts(operation, attempt, err == nil)
+		}
+	}()
+
+	for ; ; attempt++ {
+		err = u.attempt(ctx, operation, opts, fn)
+		if err == nil {
+			if attempt > 1 {
+				log.Info("db transaction committed after retries", "operation", operation, "attempts", attempt)
+			}
+			return nil
+		}
+
+		if !IsRetryable(err) {
+			return timeoutError(ctx, err)
+		}
+
+		if attempt >= u.retry.MaxAttempts {
+			log.Warn("db transaction retries exhausted", "operation", operation, "attempts", attempt, "error", err)
+			return err
+		}
+
+		delay := u.retry.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			log.Warn("no time left to retry db transaction", "operation", operation, "attempts", attempt, "error", err)
+			return err
+		}
+
+		log.Warn("retrying db transaction", "operation", operation, "attempt", attempt, "delay", delay, "error", err)
+
+		if waitErr := sleep(ctx, delay); waitErr != nil {
+			return err
+		}
+	}
+}
+
+func (u *UseCase) attempt(
+	ctx context.Context,
+	operation string,
+	opts *sql.TxOptions,
+	fn func(ctx context.Context, dbTx *sql.Tx) error,
+) (err error) {
+	dbTx, err := u.db.BeginTx(ctx, u.txOptions(operation, opts))
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer u.observe(operation, time.Now(), &err)
+	defer u.rollback(ctx, dbTx)
+
+	err = u.setTimeouts(ctx, dbTx, operation)
+	if err != nil {
+		return err
+	}
+
+	err = fn(ctx, dbTx)
+	if err != nil {
+		return err
+	}
+
+	return u.commit(ctx, dbTx)
+}
+
+// observe must be deferred right after the DB transaction begins: it reports the transaction
+// as committed only if the method has returned no error. A quote is rolled back by design, so it is not reported.
+func (u *UseCase) observe(operation string, start time.Time, err *error) {
+	if errors.Is(*err, errQuoted) {
+		return
+	}
+	u.observer.ObserveDBTx(operation, time.Since(start), *err == nil)
+}
+
+func (u *UseCase) commit(ctx context.Context, tx *sql.Tx) (err error) {
+	_, span := tracing.Start(ctx, "db.Commit")
+	defer tracing.End(span, &err)
+
+	// nothing is sent if the context is done already, and the transaction is rolled back
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	return commitError(tx.Commit())
+}
+
+func (u *UseCase) rollback(ctx context.Context, tx *sql.Tx) {
 	err := tx.Rollback()
 	if err != nil && !errors.Is(err, sql.ErrTxDone) {
-		u.logger.Error("tx rollback: %s", err)
+		u.logger.Ctx(ctx).Error("tx rollback", "error", err)
 	}
 }
@@ -0,0 +1,142 @@
+package instrumented
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/metrics"
+	"github.com/kaz-as/test-transactions/internal/usecases/general"
+	"github.com/kaz-as/test-transactions/internal/usecases/queued"
+	"github.com/kaz-as/test-transactions/pkg/tracing"
+)
+
+type TransferObserver interface {
+	ObserveTransfer(operation, outcome string, value domain.Balance)
+}
+
+// UseCase traces the calls of the wrapped use case and reports the outcome of every transfer.
+type UseCase struct {
+	uc       domain.UseCase
+	observer TransferObserver
+}
+
+var _ domain.UseCase = (*UseCase)(nil)
+
+func NewUseCase(uc domain.UseCase, observer TransferObserver) *UseCase {
+	return &UseCase{
+		uc:       uc,
+		observer: observer,
+	}
+}
+
+func (u *UseCase) CreateUser(ctx context.Context, user *domain.User) (err error) {
+	value := user.Balance
+
+	ctx, span := tracing.Start(ctx, "UseCase.CreateUser", trace.WithAttributes(
+		attribute.Int64("tx.value", int64(value)),
+	))
+	defer tracing.End(span, &err)
+
+	err = u.uc.CreateUser(ctx, user)
+	u.observer.ObserveTransfer(general.OpCreateUser, Outcome(err), value)
+
+	span.SetAttributes(attribute.String("user.id", string(user.ID)))
+	return err
+}
+
+func (u *UseCase) CreateTx(ctx context.Context, tx *domain.Tx) (_ domain.Balance, _ domain.Balance, err error) {
+	ctx, span := tracing.Start(ctx, "UseCase.CreateTx", trace.WithAttributes(
+		attribute.String("tx.from", string(tx.From)),
+		attribute.String("tx.to", string(tx.To)),
+		attribute.Int64("tx.value", int64(tx.Value)),
+	))
+	defer tracing.End(span, &err)
+
+	newBalanceFrom, newBalanceTo, err := u.uc.CreateTx(ctx, tx)
+	u.observer.ObserveTransfer(general.OpCreateTx, Outcome(err), tx.Value)
+
+	span.SetAttributes(attribute.String("tx.id", tx.ID))
+	return newBalanceFrom, newBalanceTo, err
+}
+
+// QuoteTx is not observed as a transfer, since nothing is transferred.
+func (u *UseCase) QuoteTx(ctx context.Context, tx *domain.Tx) (_ domain.Quote, err error) {
+	ctx, span := tracing.Start(ctx, "UseCase.QuoteTx", trace.WithAttributes(
+		attribute.String("tx.from", string(tx.From)),
+		attribute.String("tx.to", string(tx.To)),
+		attribute.Int64("tx.value", int64(tx.Value)),
+	))
+	defer tracing.End(span, &err)
+
+	return u.uc.QuoteTx(ctx, tx)
+}
+
+func (u *UseCase) GetUser(ctx context.Context, userID domain.UserID) (_ *domain.User, err error) {
+	ctx, span := tracing.Start(ctx, "UseCase.GetUser", trace.WithAttributes(
+		attribute.String("user.id", string(userID)),
+	))
+	defer tracing.End(span, &err)
+
+	return u.uc.GetUser(ctx, userID)
+}
+
+func (u *UseCase) GetBalance(ctx context.Context, userID domain.UserID, at *time.Time) (_ domain.Balance, err error) {
+	ctx, span := tracing.Start(ctx, "UseCase.GetBalance", trace.WithAttributes(
+		attribute.String("user.id", string(userID)),
+	))
+	defer tracing.End(span, &err)
+
+	return u.uc.GetBalance(ctx, userID, at)
+}
+
+func (u *UseCase) History(ctx context.Context, userID domain.UserID, filter domain.TxFilter, limit int) (
+	_ []domain.Tx,
+	err error,
+) {
+	ctx, span := tracing.Start(ctx, "UseCase.History", trace.WithAttributes(
+		attribute.String("user.id", string(userID)),
+		attribute.Int("limit", limit),
+	))
+	defer tracing.End(span, &err)
+
+	return u.uc.History(ctx, userID, filter, limit)
+}
+
+// Outcome classifies the error returned by a transfer.
+func Outcome(err error) string {
+	switch {
+	case err == nil:
+		return metrics.OutcomeSuccess
+	case errors.Is(err, general.ErrInsufficientBalance):
+		return metrics.OutcomeInsufficientBalance
+	case errors.Is(err, general.ErrSame):
+		return metrics.OutcomeSameAccount
+	case errors.Is(err, general.ErrNegativeTx):
+		return metrics.OutcomeNegativeValue
+	case errors.Is(err, general.ErrTooMuch):
+		return metrics.OutcomeTooMuch
+	case errors.Is(err, general.ErrInvalidDetails):
+		return metrics.OutcomeInvalidDetails
+	case errors.Is(err, general.ErrDuplicateReference):
+		return metrics.OutcomeDuplicateReference
+	case errors.Is(err, general.ErrDenied):
+		return metrics.OutcomeDenied
+	case errors.Is(err, general.ErrReviewRequired):
+		return metrics.OutcomeReviewRequired
+	case errors.Is(err, general.ErrPreconditionFailed):
+		return metrics.OutcomePreconditionFailed
+	case errors.Is(err, general.ErrLockTimeout):
+		return metrics.OutcomeLockTimeout
+	case errors.Is(err, general.ErrStatementTimeout):
+		return metrics.OutcomeStatementTimeout
+	case errors.Is(err, queued.ErrQueueFull):
+		return metrics.OutcomeQueueFull
+	default:
+		return metrics.OutcomeError
+	}
+}
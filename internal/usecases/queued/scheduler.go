@@ -0,0 +1,107 @@
+package queued
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var ErrQueueFull = errors.New("too many requests queued for the account")
+
+// queue is the turn of a key: the holder has put the token in it.
+type queue struct {
+	turn chan struct{}
+	// n counts the holder and the waiters
+	n int
+}
+
+// Scheduler runs the requests of a key one at a time, the waiting ones in the order they came.
+// It is safe for concurrent use.
+type Scheduler struct {
+	depth int
+
+	mu     sync.Mutex
+	queues map[string]*queue
+}
+
+// NewScheduler returns the scheduler letting up to depth requests of a key wait for the one in progress.
+func NewScheduler(depth int) *Scheduler {
+	return &Scheduler{
+		depth:  depth,
+		queues: make(map[string]*queue),
+	}
+}
+
+// Acquire waits for the turn of every key, taking them in order, so the requests of several keys
+// cannot deadlock. It fails fast with ErrQueueFull if a key has depth requests waiting already.
+// The returned func releases the keys.
+func (s *Scheduler) Acquire(ctx context.Context, keys ...string) (release func(), err error) {
+	keys = append([]string(nil), keys...)
+	sort.Strings(keys)
+
+	var acquired []string
+	release = func() {
+		for i := len(acquired) - 1; i >= 0; i-- {
+			s.release(acquired[i])
+		}
+	}
+
+	for i, key := range keys {
+		if i > 0 && key == keys[i-1] {
+			continue
+		}
+
+		if err := s.acquire(ctx, key); err != nil {
+			release()
+			return nil, err
+		}
+		acquired = append(acquired, key)
+	}
+
+	return release, nil
+}
+
+func (s *Scheduler) acquire(ctx context.Context, key string) error {
+	s.mu.Lock()
+	q, ok := s.queues[key]
+	if !ok {
+		q = &queue{turn: make(chan struct{}, 1)}
+		s.queues[key] = q
+	}
+	if q.n > s.depth {
+		s.mu.Unlock()
+		return fmt.Errorf("user id=%s: %w", key, ErrQueueFull)
+	}
+	q.n++
+	s.mu.Unlock()
+
+	select {
+	case q.turn <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		s.leave(key, q)
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) release(key string) {
+	s.mu.Lock()
+	q := s.queues[key]
+	s.mu.Unlock()
+
+	<-q.turn
+	s.leave(key, q)
+}
+
+// leave forgets the key once nobody holds or waits for it.
+func (s *Scheduler) leave(key string, q *queue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q.n--
+	if q.n == 0 {
+		delete(s.queues, key)
+	}
+}
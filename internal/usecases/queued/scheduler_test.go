@@ -0,0 +1,87 @@
+package queued
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler(t *testing.T) {
+	s := NewScheduler(1)
+	ctx := context.Background()
+
+	release, err := s.Acquire(ctx, "b", "a")
+	require.NoError(t, err)
+
+	other, err := s.Acquire(ctx, "c")
+	require.NoError(t, err, "the other keys are not queued")
+	other()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r, err := s.Acquire(ctx, "a")
+		assert.NoError(t, err)
+		r()
+	}()
+
+	// the goroutine above is the waiter of a
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.queues["a"].n == 2
+	}, time.Second, time.Millisecond)
+
+	_, err = s.Acquire(ctx, "b", "a")
+	assert.ErrorIs(t, err, ErrQueueFull)
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = s.Acquire(timeout, "b")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release()
+	<-done
+
+	assert.Empty(t, s.queues, "the keys nobody waits for are forgotten")
+}
+
+func TestSchedulerSerializes(t *testing.T) {
+	s := NewScheduler(100)
+
+	var (
+		wg      sync.WaitGroup
+		running int
+		mu      sync.Mutex
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			keys := []string{"a", "b"}
+			if i%2 == 0 {
+				keys = []string{"b", "a"}
+			}
+
+			release, err := s.Acquire(context.Background(), keys...)
+			require.NoError(t, err)
+			defer release()
+
+			mu.Lock()
+			running++
+			assert.Equal(t, 1, running)
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+}
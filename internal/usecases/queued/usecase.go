@@ -0,0 +1,60 @@
+// Package queued serializes the transfers of every account in the app instance, so the requests on a hot account
+// wait in memory for their turn instead of waiting for its row lock with a DB connection each.
+package queued
+
+import (
+	"context"
+
+	"github.com/kaz-as/test-transactions/domain"
+	"github.com/kaz-as/test-transactions/internal/usecases/general"
+)
+
+// UseCase runs the transfers and the quotes after the ones in progress on their accounts; the rest of the calls
+// go to the wrapped use case as they are. The primary user is not queued, as its balance is sharded.
+type UseCase struct {
+	domain.UseCase
+
+	scheduler *Scheduler
+}
+
+var _ domain.UseCase = (*UseCase)(nil)
+
+// NewUseCase returns the use case letting up to depth requests wait for an account; the next ones fail
+// with ErrQueueFull at once.
+func NewUseCase(uc domain.UseCase, depth int) *UseCase {
+	return &UseCase{
+		UseCase:   uc,
+		scheduler: NewScheduler(depth),
+	}
+}
+
+func (u *UseCase) CreateTx(ctx context.Context, tx *domain.Tx) (domain.Balance, domain.Balance, error) {
+	release, err := u.scheduler.Acquire(ctx, keys(tx)...)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer release()
+
+	return u.UseCase.CreateTx(ctx, tx)
+}
+
+func (u *UseCase) QuoteTx(ctx context.Context, tx *domain.Tx) (domain.Quote, error) {
+	release, err := u.scheduler.Acquire(ctx, keys(tx)...)
+	if err != nil {
+		return domain.Quote{}, err
+	}
+	defer release()
+
+	return u.UseCase.QuoteTx(ctx, tx)
+}
+
+func keys(tx *domain.Tx) []string {
+	var keys []string
+	for _, id := range []domain.UserID{tx.From, tx.To} {
+		if id != general.PrimaryUserID {
+			keys = append(keys, string(id))
+		}
+	}
+
+	return keys
+}
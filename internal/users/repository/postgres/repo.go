@@ -4,12 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/rand"
 	"time"
 
 	"github.com/kaz-as/test-transactions/domain"
 	"github.com/kaz-as/test-transactions/pkg/logger"
+	"github.com/kaz-as/test-transactions/pkg/tracing"
 )
 
 type userRepo struct {
@@ -22,8 +24,11 @@ func NewRepo(log logger.Interface) domain.UsersRepository {
 	}
 }
 
-func (u *userRepo) Store(ctx context.Context, tx *sql.Tx, user *domain.User) error {
-	query := `INSERT INTO users (id, balance) VALUES ($1, $2)`
+func (u *userRepo) Store(ctx context.Context, tx *sql.Tx, user *domain.User) (err error) {
+	ctx, span := tracing.Start(ctx, "usersRepo.Store")
+	defer tracing.End(span, &err)
+
+	query := `INSERT INTO users (id, balance, seq) VALUES ($1, $2, $3)`
 
 	src := rand.New(rand.NewSource(time.Now().UnixNano() + int64(user.Balance)))
 	uid, err := generateUID(src)
@@ -31,33 +36,44 @@ func (u *userRepo) Store(ctx context.Context, tx *sql.Tx, user *domain.User) err
 		return fmt.Errorf("generate uid: %w", err)
 	}
 
-	stmt, err := tx.PrepareContext(ctx, query)
-	if err != nil {
-		return fmt.Errorf("prepare: %w", err)
-	}
-	defer func() {
-		err := stmt.Close()
-		if err != nil {
-			u.log.Error("close stmt: %s", err)
-		}
-	}()
-
-	_, err = stmt.ExecContext(ctx, string(uid), int64(user.Balance))
+	_, err = tx.ExecContext(ctx, query, string(uid), int64(user.Balance), user.Seq)
 	if err != nil {
 		return fmt.Errorf("exec: %w", err)
 	}
 
 	user.ID = uid
+	// the column default
+	user.Version = 1
 	return nil
 }
 
-func (u *userRepo) GetForUpdate(ctx context.Context, tx *sql.Tx, userID domain.UserID) (*domain.User, error) {
-	query := `SELECT * FROM users WHERE id = $1 FOR NO KEY UPDATE`
+func (u *userRepo) Get(ctx context.Context, tx *sql.Tx, userID domain.UserID) (_ *domain.User, err error) {
+	ctx, span := tracing.Start(ctx, "usersRepo.Get")
+	defer tracing.End(span, &err)
+
+	query := `SELECT id, balance, version, seq FROM users WHERE id = $1`
+
+	row := tx.QueryRowContext(ctx, query, string(userID))
+
+	user := domain.User{}
+	err = row.Scan((*string)(&user.ID), (*int64)(&user.Balance), &user.Version, &user.Seq)
+	if err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	return &user, nil
+}
+
+func (u *userRepo) GetForUpdate(ctx context.Context, tx *sql.Tx, userID domain.UserID) (_ *domain.User, err error) {
+	ctx, span := tracing.Start(ctx, "usersRepo.GetForUpdate")
+	defer tracing.End(span, &err)
+
+	query := `SELECT id, balance, version, seq FROM users WHERE id = $1 FOR NO KEY UPDATE`
 
 	row := tx.QueryRowContext(ctx, query, string(userID))
 
 	user := domain.User{}
-	err := row.Scan((*string)(&user.ID), (*int64)(&user.Balance))
+	err = row.Scan((*string)(&user.ID), (*int64)(&user.Balance), &user.Version, &user.Seq)
 	if err != nil {
 		return nil, fmt.Errorf("scan: %w", err)
 	}
@@ -65,30 +81,93 @@ func (u *userRepo) GetForUpdate(ctx context.Context, tx *sql.Tx, userID domain.U
 	return &user, nil
 }
 
-func (u *userRepo) Update(ctx context.Context, tx *sql.Tx, user *domain.User) error {
-	query := `UPDATE users SET balance = $1 WHERE id = $2`
+func (u *userRepo) Update(ctx context.Context, tx *sql.Tx, user *domain.User) (err error) {
+	ctx, span := tracing.Start(ctx, "usersRepo.Update")
+	defer tracing.End(span, &err)
 
-	stmt, err := tx.PrepareContext(ctx, query)
+	query := `UPDATE users SET balance = $1, seq = $3, version = version + 1 WHERE id = $2 RETURNING version`
+
+	err = tx.QueryRowContext(ctx, query, int64(user.Balance), string(user.ID), user.Seq).Scan(&user.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.New("wierd behaviour: no user updated")
+	}
 	if err != nil {
-		return fmt.Errorf("prepare: %w", err)
+		return fmt.Errorf("scan: %w", err)
+	}
+
+	return nil
+}
+
+func (u *userRepo) NextSeq(ctx context.Context, tx *sql.Tx, userID domain.UserID) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "usersRepo.NextSeq")
+	defer tracing.End(span, &err)
+
+	query := `UPDATE users SET seq = seq + 1 WHERE id = $1 RETURNING seq`
+
+	var seq int64
+	err = tx.QueryRowContext(ctx, query, string(userID)).Scan(&seq)
+	if err != nil {
+		return 0, fmt.Errorf("scan: %w", err)
+	}
+
+	return seq, nil
+}
+
+// Discrepancies takes the initial balances from the snapshots as of -infinity: only the primary user has one.
+func (u *userRepo) Discrepancies(ctx context.Context, tx *sql.Tx) (_ []domain.Discrepancy, err error) {
+	ctx, span := tracing.Start(ctx, "usersRepo.Discrepancies")
+	defer tracing.End(span, &err)
+
+	query := `
+WITH moves AS (
+    SELECT "to" AS user_id, value AS delta FROM transactions
+    UNION ALL
+    SELECT "from", -value FROM transactions
+),
+flows AS (
+    SELECT user_id, SUM(delta) AS delta FROM moves GROUP BY user_id
+),
+balances AS (
+    -- the primary user keeps its balance in the shards
+    SELECT id, (balance + CASE
+        WHEN id = '00000000000000000000000000000000' THEN (SELECT COALESCE(SUM(balance), 0) FROM primary_shards)
+        ELSE 0
+    END)::BIGINT AS balance
+    FROM users
+)
+SELECT u.id, u.balance, (COALESCE(s.balance, 0) + COALESCE(f.delta, 0))::BIGINT AS expected
+FROM balances u
+LEFT JOIN flows f ON f.user_id = u.id
+LEFT JOIN balance_snapshots s ON s.user_id = u.id AND s.taken_at = '-infinity'
+WHERE u.balance <> COALESCE(s.balance, 0) + COALESCE(f.delta, 0)
+ORDER BY u.id`
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
 	}
 	defer func() {
-		err := stmt.Close()
+		err := rows.Close()
 		if err != nil {
-			u.log.Error("close stmt: %s", err)
+			u.log.Ctx(ctx).Error("close rows", "error", err)
 		}
 	}()
 
-	res, err := stmt.ExecContext(ctx, int64(user.Balance), string(user.ID))
-	if err != nil {
-		return fmt.Errorf("exec: %w", err)
+	var res []domain.Discrepancy
+	for rows.Next() {
+		d := domain.Discrepancy{}
+		err = rows.Scan((*string)(&d.UserID), (*int64)(&d.Balance), (*int64)(&d.Expected))
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		res = append(res, d)
 	}
 
-	if affected, _ := res.RowsAffected(); affected != 1 {
-		return fmt.Errorf("wierd behaviour: affected %d rows", affected)
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
 	}
 
-	return nil
+	return res, nil
 }
 
 func generateUID(src *rand.Rand) (domain.UserID, error) {
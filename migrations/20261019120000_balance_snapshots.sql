@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The naive timestamps stored before are taken as UTC, the zone the app has always written them in.
+-- If a deployment wrote them in another zone, replace 'UTC' with it before migrating, or the history shifts.
+ALTER TABLE transactions
+    ALTER COLUMN timestamp TYPE TIMESTAMPTZ USING timestamp AT TIME ZONE 'UTC';
+
+CREATE INDEX IF NOT EXISTS transactions_from_timestamp_idx ON transactions ("from", timestamp);
+CREATE INDEX IF NOT EXISTS transactions_to_timestamp_idx ON transactions ("to", timestamp);
+
+CREATE TABLE IF NOT EXISTS balance_snapshots
+(
+    user_id  CHAR(32) REFERENCES users NOT NULL,
+    taken_at TIMESTAMPTZ               NOT NULL,
+    balance  BIGINT                    NOT NULL,
+    PRIMARY KEY (user_id, taken_at)
+);
+
+-- the primary user owns its initial money without any transaction
+INSERT INTO balance_snapshots (user_id, taken_at, balance)
+VALUES ('00000000000000000000000000000000', '-infinity', 1000000000);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS balance_snapshots CASCADE;
+
+DROP INDEX IF EXISTS transactions_to_timestamp_idx;
+DROP INDEX IF EXISTS transactions_from_timestamp_idx;
+
+ALTER TABLE transactions
+    ALTER COLUMN timestamp TYPE TIMESTAMP USING timestamp AT TIME ZONE 'UTC';
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS admin_audit
+(
+    id       BIGSERIAL PRIMARY KEY,
+    at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    operator TEXT        NOT NULL,
+    action   TEXT        NOT NULL,
+    reason   TEXT        NOT NULL DEFAULT '',
+    params   JSONB       NOT NULL DEFAULT '{}',
+    -- empty if the action succeeded
+    error    TEXT        NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS admin_audit_at_idx ON admin_audit (at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS admin_audit CASCADE;
+-- +goose StatementEnd
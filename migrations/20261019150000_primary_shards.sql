@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the balance of the primary user moves to the shards; the old instances must be stopped before,
+-- as they would update the balance of the users row
+CREATE TABLE IF NOT EXISTS primary_shards
+(
+    id      INT PRIMARY KEY NOT NULL,
+    balance BIGINT          NOT NULL
+);
+
+INSERT INTO primary_shards (id, balance)
+SELECT g, balance / 16 + CASE WHEN g = 0 THEN balance % 16 ELSE 0 END
+FROM users, generate_series(0, 15) g
+WHERE id = '00000000000000000000000000000000';
+
+UPDATE users SET balance = 0 WHERE id = '00000000000000000000000000000000';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE users
+SET balance = (SELECT COALESCE(SUM(balance), 0) FROM primary_shards)
+WHERE id = '00000000000000000000000000000000';
+
+DROP TABLE IF EXISTS primary_shards CASCADE;
+-- +goose StatementEnd
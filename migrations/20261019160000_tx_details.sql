@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE transactions
+    ADD COLUMN description        TEXT  NOT NULL DEFAULT '',
+    ADD COLUMN external_reference TEXT,
+    ADD COLUMN metadata           JSONB NOT NULL DEFAULT '{}';
+
+-- the reference is a dedupe key of the sender
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_from_external_reference_idx
+    ON transactions ("from", external_reference) WHERE external_reference IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS transactions_metadata_idx ON transactions USING GIN (metadata jsonb_path_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS transactions_metadata_idx;
+DROP INDEX IF EXISTS transactions_from_external_reference_idx;
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS metadata,
+    DROP COLUMN IF EXISTS external_reference,
+    DROP COLUMN IF EXISTS description;
+-- +goose StatementEnd
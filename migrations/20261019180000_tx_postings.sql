@@ -0,0 +1,69 @@
+-- +goose Up
+-- +goose StatementBegin
+-- every transaction is numbered among the ones of each of its users, with the balance of the user after it;
+-- the balance of the primary user is the sum of its shards
+ALTER TABLE users ADD COLUMN seq BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE transactions
+    ADD COLUMN from_seq     BIGINT,
+    ADD COLUMN from_balance BIGINT,
+    ADD COLUMN to_seq       BIGINT,
+    ADD COLUMN to_balance   BIGINT;
+
+-- the history is numbered in the order of the balance events, the balances are counted back from the current ones
+CREATE TEMPORARY TABLE postings AS
+WITH moves AS (
+    SELECT id, timestamp, "from" AS user_id, 'from' AS side, -value AS delta FROM transactions
+    UNION ALL
+    SELECT id, timestamp, "to", 'to', value FROM transactions
+)
+SELECT m.id, m.user_id, m.side,
+       ROW_NUMBER() OVER w AS seq,
+       (u.balance - SUM(m.delta) OVER (PARTITION BY m.user_id) + SUM(m.delta) OVER w)::BIGINT AS balance
+FROM moves m
+JOIN (
+    SELECT id, CASE
+        WHEN id = '00000000000000000000000000000000' THEN (SELECT COALESCE(SUM(balance), 0) FROM primary_shards)
+        ELSE balance
+    END AS balance
+    FROM users
+) u ON u.id = m.user_id
+WINDOW w AS (PARTITION BY m.user_id ORDER BY m.timestamp, m.id ROWS UNBOUNDED PRECEDING);
+
+UPDATE transactions t SET from_seq = p.seq, from_balance = p.balance
+FROM postings p
+WHERE p.id = t.id AND p.side = 'from';
+
+UPDATE transactions t SET to_seq = p.seq, to_balance = p.balance
+FROM postings p
+WHERE p.id = t.id AND p.side = 'to';
+
+UPDATE users u SET seq = p.seq
+FROM (SELECT user_id, MAX(seq) AS seq FROM postings GROUP BY user_id) p
+WHERE p.user_id = u.id;
+
+DROP TABLE postings;
+
+ALTER TABLE transactions
+    ALTER COLUMN from_seq SET NOT NULL,
+    ALTER COLUMN from_balance SET NOT NULL,
+    ALTER COLUMN to_seq SET NOT NULL,
+    ALTER COLUMN to_balance SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_from_seq_idx ON transactions ("from", from_seq);
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_to_seq_idx ON transactions ("to", to_seq);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS transactions_to_seq_idx;
+DROP INDEX IF EXISTS transactions_from_seq_idx;
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS to_balance,
+    DROP COLUMN IF EXISTS to_seq,
+    DROP COLUMN IF EXISTS from_balance,
+    DROP COLUMN IF EXISTS from_seq;
+
+ALTER TABLE users DROP COLUMN IF EXISTS seq;
+-- +goose StatementEnd
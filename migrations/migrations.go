@@ -0,0 +1,34 @@
+// Package migrations keeps the goose migrations of the DB schema: the Postgres ones in the root
+// and the SQLite ones in the sqlite directory.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// Version is the latest migration the app is built for. It must be updated with every new migration.
+const Version int64 = 20261019180000
+
+// SQLiteVersion is the latest SQLite migration; every Postgres migration needs its SQLite counterpart.
+const SQLiteVersion int64 = 20261019180000
+
+// FS keeps the migration files, so the binary can migrate the DB by itself.
+//
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFS embed.FS
+
+// SQLiteFS keeps the SQLite migration files.
+var SQLiteFS = mustSub(sqliteFS, "sqlite")
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+
+	return sub
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"io/fs"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func latest(t *testing.T, fsys fs.FS) int64 {
+	names, err := fs.Glob(fsys, "*.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, names)
+
+	var latest int64
+	for _, name := range names {
+		v, err := strconv.ParseInt(strings.SplitN(name, "_", 2)[0], 10, 64)
+		require.NoError(t, err, name)
+		if v > latest {
+			latest = v
+		}
+	}
+
+	return latest
+}
+
+func TestVersion(t *testing.T) {
+	assert.Equal(t, latest(t, FS), Version, "Version must be the latest embedded migration")
+	assert.Equal(t, latest(t, SQLiteFS), SQLiteVersion, "SQLiteVersion must be the latest embedded SQLite migration")
+}
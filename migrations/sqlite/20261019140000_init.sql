@@ -0,0 +1,58 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the schema of the Postgres migrations up to 20261019130000_admin_audit;
+-- timestamps are Unix microseconds in UTC, -infinity is the least integer
+CREATE TABLE IF NOT EXISTS users
+(
+    id      TEXT PRIMARY KEY NOT NULL,
+    balance INTEGER          NOT NULL
+);
+
+INSERT INTO users (id, balance) VALUES ('00000000000000000000000000000000', 1000000000);
+
+CREATE TABLE IF NOT EXISTS transactions
+(
+    id        TEXT PRIMARY KEY          NOT NULL,
+    "from"    TEXT REFERENCES users     NOT NULL,
+    "to"      TEXT REFERENCES users     NOT NULL,
+    value     INTEGER                   NOT NULL,
+    timestamp INTEGER                   NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS transactions_from_timestamp_idx ON transactions ("from", timestamp);
+CREATE INDEX IF NOT EXISTS transactions_to_timestamp_idx ON transactions ("to", timestamp);
+
+CREATE TABLE IF NOT EXISTS balance_snapshots
+(
+    user_id  TEXT REFERENCES users NOT NULL,
+    taken_at INTEGER               NOT NULL,
+    balance  INTEGER               NOT NULL,
+    PRIMARY KEY (user_id, taken_at)
+);
+
+-- the primary user owns its initial money without any transaction
+INSERT INTO balance_snapshots (user_id, taken_at, balance)
+VALUES ('00000000000000000000000000000000', -9223372036854775808, 1000000000);
+
+CREATE TABLE IF NOT EXISTS admin_audit
+(
+    id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    at       INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000000 AS INTEGER)),
+    operator TEXT    NOT NULL,
+    action   TEXT    NOT NULL,
+    reason   TEXT    NOT NULL DEFAULT '',
+    params   TEXT    NOT NULL DEFAULT '{}',
+    -- empty if the action succeeded
+    error    TEXT    NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS admin_audit_at_idx ON admin_audit (at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS admin_audit;
+DROP TABLE IF EXISTS balance_snapshots;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS users;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS primary_shards
+(
+    id      INTEGER PRIMARY KEY NOT NULL,
+    balance INTEGER             NOT NULL
+);
+
+INSERT INTO primary_shards (id, balance)
+WITH RECURSIVE g(n) AS (SELECT 0 UNION ALL SELECT n + 1 FROM g WHERE n < 15)
+SELECT g.n, u.balance / 16 + CASE WHEN g.n = 0 THEN u.balance % 16 ELSE 0 END
+FROM users u, g
+WHERE u.id = '00000000000000000000000000000000';
+
+UPDATE users SET balance = 0 WHERE id = '00000000000000000000000000000000';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE users
+SET balance = (SELECT COALESCE(SUM(balance), 0) FROM primary_shards)
+WHERE id = '00000000000000000000000000000000';
+
+DROP TABLE IF EXISTS primary_shards;
+-- +goose StatementEnd
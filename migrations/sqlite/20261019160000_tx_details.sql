@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE transactions ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN external_reference TEXT;
+-- a JSON object
+ALTER TABLE transactions ADD COLUMN metadata TEXT NOT NULL DEFAULT '{}';
+
+-- the reference is a dedupe key of the sender
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_from_external_reference_idx
+    ON transactions ("from", external_reference) WHERE external_reference IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS transactions_from_external_reference_idx;
+
+ALTER TABLE transactions DROP COLUMN metadata;
+ALTER TABLE transactions DROP COLUMN external_reference;
+ALTER TABLE transactions DROP COLUMN description;
+-- +goose StatementEnd
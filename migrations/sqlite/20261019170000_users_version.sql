@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- every update of the balance increments the version; the primary user keeps its balance in the shards,
+-- so it is not versioned and has 0
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+UPDATE users SET version = 0 WHERE id = '00000000000000000000000000000000';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN version;
+-- +goose StatementEnd
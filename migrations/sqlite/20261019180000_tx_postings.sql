@@ -0,0 +1,58 @@
+-- +goose Up
+-- +goose StatementBegin
+-- every transaction is numbered among the ones of each of its users, with the balance of the user after it;
+-- the balance of the primary user is the sum of its shards
+ALTER TABLE users ADD COLUMN seq INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE transactions ADD COLUMN from_seq INTEGER;
+ALTER TABLE transactions ADD COLUMN from_balance INTEGER;
+ALTER TABLE transactions ADD COLUMN to_seq INTEGER;
+ALTER TABLE transactions ADD COLUMN to_balance INTEGER;
+
+-- the history is numbered in the order of the balance events, the balances are counted back from the current ones
+CREATE TEMPORARY TABLE postings AS
+WITH moves AS (
+    SELECT id, timestamp, "from" AS user_id, 'from' AS side, -value AS delta FROM transactions
+    UNION ALL
+    SELECT id, timestamp, "to", 'to', value FROM transactions
+)
+SELECT m.id, m.user_id, m.side,
+       ROW_NUMBER() OVER w AS seq,
+       u.balance - SUM(m.delta) OVER (PARTITION BY m.user_id) + SUM(m.delta) OVER w AS balance
+FROM moves m
+JOIN (
+    SELECT id, CASE
+        WHEN id = '00000000000000000000000000000000' THEN (SELECT COALESCE(SUM(balance), 0) FROM primary_shards)
+        ELSE balance
+    END AS balance
+    FROM users
+) u ON u.id = m.user_id
+WINDOW w AS (PARTITION BY m.user_id ORDER BY m.timestamp, m.id ROWS UNBOUNDED PRECEDING);
+
+UPDATE transactions SET
+    from_seq     = (SELECT seq FROM postings p WHERE p.id = transactions.id AND p.side = 'from'),
+    from_balance = (SELECT balance FROM postings p WHERE p.id = transactions.id AND p.side = 'from'),
+    to_seq       = (SELECT seq FROM postings p WHERE p.id = transactions.id AND p.side = 'to'),
+    to_balance   = (SELECT balance FROM postings p WHERE p.id = transactions.id AND p.side = 'to');
+
+UPDATE users SET seq = (SELECT MAX(seq) FROM postings p WHERE p.user_id = users.id)
+WHERE id IN (SELECT user_id FROM postings);
+
+DROP TABLE postings;
+
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_from_seq_idx ON transactions ("from", from_seq);
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_to_seq_idx ON transactions ("to", to_seq);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS transactions_to_seq_idx;
+DROP INDEX IF EXISTS transactions_from_seq_idx;
+
+ALTER TABLE transactions DROP COLUMN to_balance;
+ALTER TABLE transactions DROP COLUMN to_seq;
+ALTER TABLE transactions DROP COLUMN from_balance;
+ALTER TABLE transactions DROP COLUMN from_seq;
+
+ALTER TABLE users DROP COLUMN seq;
+-- +goose StatementEnd
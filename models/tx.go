@@ -19,11 +19,25 @@ import (
 // swagger:model Tx
 type Tx struct {
 
+	// description
+	// Max Length: 1024
+	Description string `json:"description,omitempty"`
+
+	// The transfer fails with 412 if the sender balance is not this one.
+	ExpectedBalance *int64 `json:"expected_balance,omitempty"`
+
+	// Unique among the transactions of the sender, so a repeated request is rejected with 409.
+	// Max Length: 128
+	ExternalReference string `json:"external_reference,omitempty"`
+
 	// from
 	// Required: true
 	// Pattern: ^[0-9a-f]{32}$
 	From *string `json:"from"`
 
+	// Free-form data stored with the transaction, at most 4096 bytes as JSON.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+
 	// to
 	// Required: true
 	// Pattern: ^[0-9a-f]{32}$
@@ -39,6 +53,14 @@ type Tx struct {
 func (m *Tx) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateDescription(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateExternalReference(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateFrom(formats); err != nil {
 		res = append(res, err)
 	}
@@ -57,6 +79,30 @@ func (m *Tx) Validate(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *Tx) validateDescription(formats strfmt.Registry) error {
+	if swag.IsZero(m.Description) { // not required
+		return nil
+	}
+
+	if err := validate.MaxLength("description", "body", m.Description, 1024); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Tx) validateExternalReference(formats strfmt.Registry) error {
+	if swag.IsZero(m.ExternalReference) { // not required
+		return nil
+	}
+
+	if err := validate.MaxLength("external_reference", "body", m.ExternalReference, 128); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (m *Tx) validateFrom(formats strfmt.Registry) error {
 
 	if err := validate.Required("from", "body", m.From); err != nil {
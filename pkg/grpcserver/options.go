@@ -0,0 +1,21 @@
+package grpcserver
+
+import (
+	"net"
+
+	"github.com/kaz-as/test-transactions/pkg/logger"
+)
+
+type Option func(*Server)
+
+func Port(port string) Option {
+	return func(s *Server) {
+		s.addr = net.JoinHostPort("", port)
+	}
+}
+
+func Logger(l logger.Interface) Option {
+	return func(s *Server) {
+		s.printf = logger.Printf(l.Info)
+	}
+}
@@ -0,0 +1,74 @@
+package grpcserver
+
+import (
+	"log"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	_defaultAddr            = ":9090"
+	_defaultShutdownTimeout = 3 * time.Second
+)
+
+// Server runs a gRPC server the same way httpserver.Server runs an HTTP one.
+type Server struct {
+	server          *grpc.Server
+	addr            string
+	notify          chan error
+	shutdownTimeout time.Duration
+	printf          func(string, ...interface{})
+}
+
+func New(server *grpc.Server, opts ...Option) *Server {
+	s := &Server{
+		server:          server,
+		addr:            _defaultAddr,
+		notify:          make(chan error, 1),
+		shutdownTimeout: _defaultShutdownTimeout,
+		printf:          log.Printf,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *Server) Start() {
+	go func() {
+		s.printf("starting grpc server: '%s'", s.addr)
+
+		lis, err := net.Listen("tcp", s.addr)
+		if err != nil {
+			s.notify <- err
+			close(s.notify)
+			return
+		}
+
+		s.notify <- s.server.Serve(lis)
+		close(s.notify)
+	}()
+}
+
+func (s *Server) Notify() <-chan error {
+	return s.notify
+}
+
+// Shutdown waits for the calls in progress, but stops them forcibly after the shutdown timeout.
+func (s *Server) Shutdown() {
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(s.shutdownTimeout):
+		s.server.Stop()
+	}
+}
@@ -25,10 +25,17 @@ func (s srvErrLog) Write(p []byte) (int, error) {
 	return len(p), nil
 }
 
+// OnShutdown registers f to be called at the start of Shutdown, e.g. to end long-lived responses.
+func OnShutdown(f func()) Option {
+	return func(s *Server) {
+		s.server.RegisterOnShutdown(f)
+	}
+}
+
 func Logger(l logger.Interface) Option {
 	return func(s *Server) {
 		lg := log.New(srvErrLog{logger: l}, "server error: ", log.LstdFlags|log.Llongfile)
 		s.server.ErrorLog = lg
-		s.printf = l.Info
+		s.printf = logger.Printf(l.Info)
 	}
 }
@@ -1,28 +1,57 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
 )
 
+// Interface is a leveled structured logger.
+// Every message is followed by key/value pairs: l.Info("tx created", "id", id, "value", value).
 type Interface interface {
-	Debug(message interface{}, args ...interface{})
-	Info(message string, args ...interface{})
-	Warn(message string, args ...interface{})
-	Error(message interface{}, args ...interface{})
-	Fatal(message interface{}, args ...interface{})
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+	Fatal(msg string, keyvals ...interface{})
+
+	// With returns a logger that adds the key/value pairs to every message.
+	With(keyvals ...interface{}) Interface
+	// Ctx returns a logger that adds the key/value pairs stored in the context by ContextWith.
+	Ctx(ctx context.Context) Interface
 }
 
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
 type Logger struct {
-	logger *zerolog.Logger
+	logger zerolog.Logger
 }
 
 var _ Interface = (*Logger)(nil)
 
-func New(level string) *Logger {
+func New(level, format string) *Logger {
+	return NewWriter(os.Stdout, level, format)
+}
+
+// NewWriter returns a logger that writes to w instead of stdout, e.g. for a command-line tool with its own output.
+func NewWriter(w io.Writer, level, format string) *Logger {
+	out := w
+	if strings.ToLower(format) == FormatConsole {
+		out = zerolog.ConsoleWriter{Out: w, TimeFormat: time.RFC3339}
+	}
+
+	return newLogger(level, out)
+}
+
+func newLogger(level string, out io.Writer) *Logger {
 	var l zerolog.Level
 
 	switch strings.ToLower(level) {
@@ -38,59 +67,127 @@ func New(level string) *Logger {
 		l = zerolog.InfoLevel
 	}
 
-	zerolog.SetGlobalLevel(l)
-
-	skipFrameCount := 3
-	logger := zerolog.New(os.Stdout).With().Timestamp().
+	skipFrameCount := 2
+	logger := zerolog.New(out).Level(l).With().Timestamp().
 		CallerWithSkipFrameCount(zerolog.CallerSkipFrameCount + skipFrameCount).
 		Logger()
 
 	return &Logger{
-		logger: &logger,
+		logger: logger,
 	}
 }
 
-func (l *Logger) Debug(message interface{}, args ...interface{}) {
-	l.msg("debug", message, args...)
+// Nop returns a logger that writes nothing.
+func Nop() *Logger {
+	return &Logger{
+		logger: zerolog.Nop(),
+	}
 }
 
-func (l *Logger) Info(message string, args ...interface{}) {
-	l.log(message, args...)
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(l.logger.Debug(), msg, keyvals)
 }
 
-func (l *Logger) Warn(message string, args ...interface{}) {
-	l.log(message, args...)
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(l.logger.Info(), msg, keyvals)
 }
 
-func (l *Logger) Error(message interface{}, args ...interface{}) {
-	if l.logger.GetLevel() == zerolog.DebugLevel {
-		l.Debug(message, args...)
-	}
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(l.logger.Warn(), msg, keyvals)
+}
 
-	l.msg("error", message, args...)
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(l.logger.Error(), msg, keyvals)
 }
 
-func (l *Logger) Fatal(message interface{}, args ...interface{}) {
-	l.msg("fatal", message, args...)
+func (l *Logger) Fatal(msg string, keyvals ...interface{}) {
+	l.log(l.logger.WithLevel(zerolog.FatalLevel), msg, keyvals)
 
 	os.Exit(1)
 }
 
-func (l *Logger) log(message string, args ...interface{}) {
-	if len(args) == 0 {
-		l.logger.Info().Msg(message)
-	} else {
-		l.logger.Info().Msgf(message, args...)
+func (l *Logger) With(keyvals ...interface{}) Interface {
+	if len(keyvals) == 0 {
+		return l
+	}
+
+	return &Logger{
+		logger: l.logger.With().Fields(fields(keyvals)).Logger(),
 	}
 }
 
-func (l *Logger) msg(level string, message interface{}, args ...interface{}) {
-	switch msg := message.(type) {
-	case error:
-		l.log(msg.Error(), args...)
-	case string:
-		l.log(msg, args...)
-	default:
-		l.log(fmt.Sprintf("%s message %v has unknown type %v", level, message, msg), args...)
+func (l *Logger) Ctx(ctx context.Context) Interface {
+	if ctx == nil {
+		return l
+	}
+
+	keyvals, _ := ctx.Value(ctxKey{}).([]interface{})
+	return l.With(keyvals...)
+}
+
+func (l *Logger) log(e *zerolog.Event, msg string, keyvals []interface{}) {
+	if len(keyvals) > 0 {
+		e = e.Fields(fields(keyvals))
+	}
+	e.Msg(msg)
+}
+
+// fields makes the key/value pairs acceptable by zerolog: a key must be a string, and the last key needs a value.
+func fields(keyvals []interface{}) []interface{} {
+	res := make([]interface{}, 0, len(keyvals)+1)
+
+	for i := 0; i < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok {
+			key = fmt.Sprint(keyvals[i])
+		}
+
+		var val interface{} = "(MISSING)"
+		if i+1 < len(keyvals) {
+			val = keyvals[i+1]
+		}
+
+		res = append(res, key, val)
+	}
+
+	return res
+}
+
+type ctxKey struct{}
+
+// ContextWith stores the key/value pairs in the context in addition to the stored ones,
+// so every logger got by Ctx adds them to its messages.
+func ContextWith(ctx context.Context, keyvals ...interface{}) context.Context {
+	prev, _ := ctx.Value(ctxKey{}).([]interface{})
+
+	all := make([]interface{}, 0, len(prev)+len(keyvals))
+	all = append(all, prev...)
+	all = append(all, keyvals...)
+
+	return context.WithValue(ctx, ctxKey{}, all)
+}
+
+// Value returns the value stored in the context by ContextWith for the key, the last one if it is stored
+// more than once.
+func Value(ctx context.Context, key string) (interface{}, bool) {
+	keyvals, _ := ctx.Value(ctxKey{}).([]interface{})
+
+	var (
+		value interface{}
+		found bool
+	)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if k, ok := keyvals[i].(string); ok && k == key {
+			value, found = keyvals[i+1], true
+		}
+	}
+
+	return value, found
+}
+
+// Printf adapts a logging method to the printf-like functions expected by other packages.
+func Printf(log func(msg string, keyvals ...interface{})) func(format string, args ...interface{}) {
+	return func(format string, args ...interface{}) {
+		log(fmt.Sprintf(format, args...))
 	}
 }
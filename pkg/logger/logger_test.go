@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var res []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		m := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		res = append(res, m)
+	}
+	return res
+}
+
+func TestLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := newLogger("info", buf)
+
+	l.Debug("hidden")
+	l.Info("info")
+	l.Warn("warn")
+	l.Error("error")
+
+	got := lines(t, buf)
+	require.Len(t, got, 3, "debug must be filtered out")
+
+	for i, level := range []string{"info", "warn", "error"} {
+		assert.Equal(t, level, got[i]["level"])
+		assert.Equal(t, level, got[i]["message"])
+		assert.Contains(t, got[i]["caller"], "logger_test.go", "caller must point to the call site")
+	}
+}
+
+func TestFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := newLogger("debug", buf)
+
+	ctx := ContextWith(context.Background(), "request_id", "abc")
+	l.With("component", "test").Ctx(ctx).Info("msg", "error", errors.New("boom"), "n", 1, "odd")
+
+	got := lines(t, buf)
+	require.Len(t, got, 1)
+	assert.Equal(t, "test", got[0]["component"])
+	assert.Equal(t, "abc", got[0]["request_id"])
+	assert.Equal(t, "boom", got[0]["error"])
+	assert.Equal(t, float64(1), got[0]["n"])
+	assert.Equal(t, "(MISSING)", got[0]["odd"])
+}
+
+func TestValue(t *testing.T) {
+	ctx := ContextWith(context.Background(), "request_id", "abc", "user_id", "1")
+	ctx = ContextWith(ctx, "request_id", "def", "odd")
+
+	v, ok := Value(ctx, "request_id")
+	assert.True(t, ok)
+	assert.Equal(t, "def", v, "the last one")
+
+	_, ok = Value(ctx, "odd")
+	assert.False(t, ok, "a key with no value")
+	_, ok = Value(context.Background(), "request_id")
+	assert.False(t, ok)
+}
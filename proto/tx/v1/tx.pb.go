@@ -0,0 +1,925 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: tx/v1/tx.proto
+
+package txv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CreateUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Balance       int64                  `protobuf:"varint,1,opt,name=balance,proto3" json:"balance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateUserRequest) Reset() {
+	*x = CreateUserRequest{}
+	mi := &file_tx_v1_tx_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateUserRequest) ProtoMessage() {}
+
+func (x *CreateUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tx_v1_tx_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateUserRequest.ProtoReflect.Descriptor instead.
+func (*CreateUserRequest) Descriptor() ([]byte, []int) {
+	return file_tx_v1_tx_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CreateUserRequest) GetBalance() int64 {
+	if x != nil {
+		return x.Balance
+	}
+	return 0
+}
+
+type CreateUserResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateUserResponse) Reset() {
+	*x = CreateUserResponse{}
+	mi := &file_tx_v1_tx_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateUserResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateUserResponse) ProtoMessage() {}
+
+func (x *CreateUserResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tx_v1_tx_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateUserResponse.ProtoReflect.Descriptor instead.
+func (*CreateUserResponse) Descriptor() ([]byte, []int) {
+	return file_tx_v1_tx_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateUserResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type CreateTxRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	From        string                 `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To          string                 `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Value       int64                  `protobuf:"varint,3,opt,name=value,proto3" json:"value,omitempty"`
+	Description string                 `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	// external_reference is unique among the transactions of the sender, so a repeated request is rejected.
+	ExternalReference string           `protobuf:"bytes,5,opt,name=external_reference,json=externalReference,proto3" json:"external_reference,omitempty"`
+	Metadata          *structpb.Struct `protobuf:"bytes,6,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	// expected_version and expected_balance are the state of the sender the transfer relies on:
+	// it fails with FAILED_PRECONDITION if the sender is not in it.
+	ExpectedVersion *int64 `protobuf:"varint,7,opt,name=expected_version,json=expectedVersion,proto3,oneof" json:"expected_version,omitempty"`
+	ExpectedBalance *int64 `protobuf:"varint,8,opt,name=expected_balance,json=expectedBalance,proto3,oneof" json:"expected_balance,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *CreateTxRequest) Reset() {
+	*x = CreateTxRequest{}
+	mi := &file_tx_v1_tx_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateTxRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTxRequest) ProtoMessage() {}
+
+func (x *CreateTxRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tx_v1_tx_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTxRequest.ProtoReflect.Descriptor instead.
+func (*CreateTxRequest) Descriptor() ([]byte, []int) {
+	return file_tx_v1_tx_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CreateTxRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *CreateTxRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *CreateTxRequest) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *CreateTxRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *CreateTxRequest) GetExternalReference() string {
+	if x != nil {
+		return x.ExternalReference
+	}
+	return ""
+}
+
+func (x *CreateTxRequest) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *CreateTxRequest) GetExpectedVersion() int64 {
+	if x != nil && x.ExpectedVersion != nil {
+		return *x.ExpectedVersion
+	}
+	return 0
+}
+
+func (x *CreateTxRequest) GetExpectedBalance() int64 {
+	if x != nil && x.ExpectedBalance != nil {
+		return *x.ExpectedBalance
+	}
+	return 0
+}
+
+type CreateTxResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	NewBalanceFrom int64                  `protobuf:"varint,2,opt,name=new_balance_from,json=newBalanceFrom,proto3" json:"new_balance_from,omitempty"`
+	NewBalanceTo   int64                  `protobuf:"varint,3,opt,name=new_balance_to,json=newBalanceTo,proto3" json:"new_balance_to,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CreateTxResponse) Reset() {
+	*x = CreateTxResponse{}
+	mi := &file_tx_v1_tx_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateTxResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTxResponse) ProtoMessage() {}
+
+func (x *CreateTxResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tx_v1_tx_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTxResponse.ProtoReflect.Descriptor instead.
+func (*CreateTxResponse) Descriptor() ([]byte, []int) {
+	return file_tx_v1_tx_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CreateTxResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *CreateTxResponse) GetNewBalanceFrom() int64 {
+	if x != nil {
+		return x.NewBalanceFrom
+	}
+	return 0
+}
+
+func (x *CreateTxResponse) GetNewBalanceTo() int64 {
+	if x != nil {
+		return x.NewBalanceTo
+	}
+	return 0
+}
+
+type QuoteTxResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	NewBalanceFrom int64                  `protobuf:"varint,1,opt,name=new_balance_from,json=newBalanceFrom,proto3" json:"new_balance_from,omitempty"`
+	NewBalanceTo   int64                  `protobuf:"varint,2,opt,name=new_balance_to,json=newBalanceTo,proto3" json:"new_balance_to,omitempty"`
+	// fee is 0: the ledger charges no fees yet.
+	Fee int64 `protobuf:"varint,3,opt,name=fee,proto3" json:"fee,omitempty"`
+	// violations are the deny and review decisions of all the rules, in their order, if the transfer
+	// would be stopped; the balances are not projected then.
+	Violations    []*Violation `protobuf:"bytes,4,rep,name=violations,proto3" json:"violations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuoteTxResponse) Reset() {
+	*x = QuoteTxResponse{}
+	mi := &file_tx_v1_tx_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuoteTxResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuoteTxResponse) ProtoMessage() {}
+
+func (x *QuoteTxResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tx_v1_tx_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuoteTxResponse.ProtoReflect.Descriptor instead.
+func (*QuoteTxResponse) Descriptor() ([]byte, []int) {
+	return file_tx_v1_tx_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *QuoteTxResponse) GetNewBalanceFrom() int64 {
+	if x != nil {
+		return x.NewBalanceFrom
+	}
+	return 0
+}
+
+func (x *QuoteTxResponse) GetNewBalanceTo() int64 {
+	if x != nil {
+		return x.NewBalanceTo
+	}
+	return 0
+}
+
+func (x *QuoteTxResponse) GetFee() int64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+func (x *QuoteTxResponse) GetViolations() []*Violation {
+	if x != nil {
+		return x.Violations
+	}
+	return nil
+}
+
+type Violation struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// decision is deny or review.
+	Decision      string `protobuf:"bytes,1,opt,name=decision,proto3" json:"decision,omitempty"`
+	Rule          string `protobuf:"bytes,2,opt,name=rule,proto3" json:"rule,omitempty"`
+	Reason        string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	Message       string `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Violation) Reset() {
+	*x = Violation{}
+	mi := &file_tx_v1_tx_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Violation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Violation) ProtoMessage() {}
+
+func (x *Violation) ProtoReflect() protoreflect.Message {
+	mi := &file_tx_v1_tx_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Violation.ProtoReflect.Descriptor instead.
+func (*Violation) Descriptor() ([]byte, []int) {
+	return file_tx_v1_tx_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Violation) GetDecision() string {
+	if x != nil {
+		return x.Decision
+	}
+	return ""
+}
+
+func (x *Violation) GetRule() string {
+	if x != nil {
+		return x.Rule
+	}
+	return ""
+}
+
+func (x *Violation) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Violation) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type GetBalanceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	At            *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=at,proto3" json:"at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBalanceRequest) Reset() {
+	*x = GetBalanceRequest{}
+	mi := &file_tx_v1_tx_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBalanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBalanceRequest) ProtoMessage() {}
+
+func (x *GetBalanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tx_v1_tx_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBalanceRequest.ProtoReflect.Descriptor instead.
+func (*GetBalanceRequest) Descriptor() ([]byte, []int) {
+	return file_tx_v1_tx_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetBalanceRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GetBalanceRequest) GetAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.At
+	}
+	return nil
+}
+
+type GetBalanceResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Id      string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Balance int64                  `protobuf:"varint,2,opt,name=balance,proto3" json:"balance,omitempty"`
+	At      *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=at,proto3" json:"at,omitempty"`
+	// version of the current balance; 0 if at is set or the user is not versioned.
+	Version       int64 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBalanceResponse) Reset() {
+	*x = GetBalanceResponse{}
+	mi := &file_tx_v1_tx_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBalanceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBalanceResponse) ProtoMessage() {}
+
+func (x *GetBalanceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tx_v1_tx_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBalanceResponse.ProtoReflect.Descriptor instead.
+func (*GetBalanceResponse) Descriptor() ([]byte, []int) {
+	return file_tx_v1_tx_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetBalanceResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GetBalanceResponse) GetBalance() int64 {
+	if x != nil {
+		return x.Balance
+	}
+	return 0
+}
+
+func (x *GetBalanceResponse) GetAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.At
+	}
+	return nil
+}
+
+func (x *GetBalanceResponse) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type GetHistoryRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// limit is 50 if not set, at most 1000.
+	Limit int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	// external_reference narrows the history down to the transaction with the reference.
+	ExternalReference string `protobuf:"bytes,3,opt,name=external_reference,json=externalReference,proto3" json:"external_reference,omitempty"`
+	// metadata narrows the history down to the transactions having all the keys with the string values.
+	Metadata      map[string]string `protobuf:"bytes,4,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetHistoryRequest) Reset() {
+	*x = GetHistoryRequest{}
+	mi := &file_tx_v1_tx_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHistoryRequest) ProtoMessage() {}
+
+func (x *GetHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tx_v1_tx_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHistoryRequest.ProtoReflect.Descriptor instead.
+func (*GetHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_tx_v1_tx_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetHistoryRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GetHistoryRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *GetHistoryRequest) GetExternalReference() string {
+	if x != nil {
+		return x.ExternalReference
+	}
+	return ""
+}
+
+func (x *GetHistoryRequest) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type GetHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transactions  []*Transaction         `protobuf:"bytes,1,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetHistoryResponse) Reset() {
+	*x = GetHistoryResponse{}
+	mi := &file_tx_v1_tx_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHistoryResponse) ProtoMessage() {}
+
+func (x *GetHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tx_v1_tx_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHistoryResponse.ProtoReflect.Descriptor instead.
+func (*GetHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_tx_v1_tx_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetHistoryResponse) GetTransactions() []*Transaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
+type Transaction struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Id                string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	From              string                 `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To                string                 `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	Value             int64                  `protobuf:"varint,4,opt,name=value,proto3" json:"value,omitempty"`
+	Timestamp         *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Description       string                 `protobuf:"bytes,6,opt,name=description,proto3" json:"description,omitempty"`
+	ExternalReference string                 `protobuf:"bytes,7,opt,name=external_reference,json=externalReference,proto3" json:"external_reference,omitempty"`
+	Metadata          *structpb.Struct       `protobuf:"bytes,8,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	// seq numbers the transactions of the user of the request from 1 with no gaps, and balance is the balance
+	// of the user after the transaction, the primary user included.
+	Seq           int64 `protobuf:"varint,9,opt,name=seq,proto3" json:"seq,omitempty"`
+	Balance       int64 `protobuf:"varint,10,opt,name=balance,proto3" json:"balance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Transaction) Reset() {
+	*x = Transaction{}
+	mi := &file_tx_v1_tx_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transaction) ProtoMessage() {}
+
+func (x *Transaction) ProtoReflect() protoreflect.Message {
+	mi := &file_tx_v1_tx_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transaction.ProtoReflect.Descriptor instead.
+func (*Transaction) Descriptor() ([]byte, []int) {
+	return file_tx_v1_tx_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Transaction) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Transaction) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *Transaction) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *Transaction) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *Transaction) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *Transaction) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Transaction) GetExternalReference() string {
+	if x != nil {
+		return x.ExternalReference
+	}
+	return ""
+}
+
+func (x *Transaction) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Transaction) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Transaction) GetBalance() int64 {
+	if x != nil {
+		return x.Balance
+	}
+	return 0
+}
+
+var File_tx_v1_tx_proto protoreflect.FileDescriptor
+
+const file_tx_v1_tx_proto_rawDesc = "" +
+	"\n" +
+	"\x0etx/v1/tx.proto\x12\x05tx.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"-\n" +
+	"\x11CreateUserRequest\x12\x18\n" +
+	"\abalance\x18\x01 \x01(\x03R\abalance\"$\n" +
+	"\x12CreateUserResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\xdb\x02\n" +
+	"\x0fCreateTxRequest\x12\x12\n" +
+	"\x04from\x18\x01 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x02 \x01(\tR\x02to\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\x03R\x05value\x12 \n" +
+	"\vdescription\x18\x04 \x01(\tR\vdescription\x12-\n" +
+	"\x12external_reference\x18\x05 \x01(\tR\x11externalReference\x123\n" +
+	"\bmetadata\x18\x06 \x01(\v2\x17.google.protobuf.StructR\bmetadata\x12.\n" +
+	"\x10expected_version\x18\a \x01(\x03H\x00R\x0fexpectedVersion\x88\x01\x01\x12.\n" +
+	"\x10expected_balance\x18\b \x01(\x03H\x01R\x0fexpectedBalance\x88\x01\x01B\x13\n" +
+	"\x11_expected_versionB\x13\n" +
+	"\x11_expected_balance\"r\n" +
+	"\x10CreateTxResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12(\n" +
+	"\x10new_balance_from\x18\x02 \x01(\x03R\x0enewBalanceFrom\x12$\n" +
+	"\x0enew_balance_to\x18\x03 \x01(\x03R\fnewBalanceTo\"\xa5\x01\n" +
+	"\x0fQuoteTxResponse\x12(\n" +
+	"\x10new_balance_from\x18\x01 \x01(\x03R\x0enewBalanceFrom\x12$\n" +
+	"\x0enew_balance_to\x18\x02 \x01(\x03R\fnewBalanceTo\x12\x10\n" +
+	"\x03fee\x18\x03 \x01(\x03R\x03fee\x120\n" +
+	"\n" +
+	"violations\x18\x04 \x03(\v2\x10.tx.v1.ViolationR\n" +
+	"violations\"m\n" +
+	"\tViolation\x12\x1a\n" +
+	"\bdecision\x18\x01 \x01(\tR\bdecision\x12\x12\n" +
+	"\x04rule\x18\x02 \x01(\tR\x04rule\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\"O\n" +
+	"\x11GetBalanceRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02at\"\x84\x01\n" +
+	"\x12GetBalanceResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\abalance\x18\x02 \x01(\x03R\abalance\x12*\n" +
+	"\x02at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x02at\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\x03R\aversion\"\xe9\x01\n" +
+	"\x11GetHistoryRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12-\n" +
+	"\x12external_reference\x18\x03 \x01(\tR\x11externalReference\x12B\n" +
+	"\bmetadata\x18\x04 \x03(\v2&.tx.v1.GetHistoryRequest.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"L\n" +
+	"\x12GetHistoryResponse\x126\n" +
+	"\ftransactions\x18\x01 \x03(\v2\x12.tx.v1.TransactionR\ftransactions\"\xc3\x02\n" +
+	"\vTransaction\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04from\x18\x02 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x03 \x01(\tR\x02to\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\x03R\x05value\x128\n" +
+	"\ttimestamp\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12 \n" +
+	"\vdescription\x18\x06 \x01(\tR\vdescription\x12-\n" +
+	"\x12external_reference\x18\a \x01(\tR\x11externalReference\x123\n" +
+	"\bmetadata\x18\b \x01(\v2\x17.google.protobuf.StructR\bmetadata\x12\x10\n" +
+	"\x03seq\x18\t \x01(\x03R\x03seq\x12\x18\n" +
+	"\abalance\x18\n" +
+	" \x01(\x03R\abalance2\xcc\x02\n" +
+	"\tTxService\x12A\n" +
+	"\n" +
+	"CreateUser\x12\x18.tx.v1.CreateUserRequest\x1a\x19.tx.v1.CreateUserResponse\x12;\n" +
+	"\bCreateTx\x12\x16.tx.v1.CreateTxRequest\x1a\x17.tx.v1.CreateTxResponse\x129\n" +
+	"\aQuoteTx\x12\x16.tx.v1.CreateTxRequest\x1a\x16.tx.v1.QuoteTxResponse\x12A\n" +
+	"\n" +
+	"GetBalance\x12\x18.tx.v1.GetBalanceRequest\x1a\x19.tx.v1.GetBalanceResponse\x12A\n" +
+	"\n" +
+	"GetHistory\x12\x18.tx.v1.GetHistoryRequest\x1a\x19.tx.v1.GetHistoryResponseB6Z4github.com/kaz-as/test-transactions/proto/tx/v1;txv1b\x06proto3"
+
+var (
+	file_tx_v1_tx_proto_rawDescOnce sync.Once
+	file_tx_v1_tx_proto_rawDescData []byte
+)
+
+func file_tx_v1_tx_proto_rawDescGZIP() []byte {
+	file_tx_v1_tx_proto_rawDescOnce.Do(func() {
+		file_tx_v1_tx_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_tx_v1_tx_proto_rawDesc), len(file_tx_v1_tx_proto_rawDesc)))
+	})
+	return file_tx_v1_tx_proto_rawDescData
+}
+
+var file_tx_v1_tx_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_tx_v1_tx_proto_goTypes = []any{
+	(*CreateUserRequest)(nil),     // 0: tx.v1.CreateUserRequest
+	(*CreateUserResponse)(nil),    // 1: tx.v1.CreateUserResponse
+	(*CreateTxRequest)(nil),       // 2: tx.v1.CreateTxRequest
+	(*CreateTxResponse)(nil),      // 3: tx.v1.CreateTxResponse
+	(*QuoteTxResponse)(nil),       // 4: tx.v1.QuoteTxResponse
+	(*Violation)(nil),             // 5: tx.v1.Violation
+	(*GetBalanceRequest)(nil),     // 6: tx.v1.GetBalanceRequest
+	(*GetBalanceResponse)(nil),    // 7: tx.v1.GetBalanceResponse
+	(*GetHistoryRequest)(nil),     // 8: tx.v1.GetHistoryRequest
+	(*GetHistoryResponse)(nil),    // 9: tx.v1.GetHistoryResponse
+	(*Transaction)(nil),           // 10: tx.v1.Transaction
+	nil,                           // 11: tx.v1.GetHistoryRequest.MetadataEntry
+	(*structpb.Struct)(nil),       // 12: google.protobuf.Struct
+	(*timestamppb.Timestamp)(nil), // 13: google.protobuf.Timestamp
+}
+var file_tx_v1_tx_proto_depIdxs = []int32{
+	12, // 0: tx.v1.CreateTxRequest.metadata:type_name -> google.protobuf.Struct
+	5,  // 1: tx.v1.QuoteTxResponse.violations:type_name -> tx.v1.Violation
+	13, // 2: tx.v1.GetBalanceRequest.at:type_name -> google.protobuf.Timestamp
+	13, // 3: tx.v1.GetBalanceResponse.at:type_name -> google.protobuf.Timestamp
+	11, // 4: tx.v1.GetHistoryRequest.metadata:type_name -> tx.v1.GetHistoryRequest.MetadataEntry
+	10, // 5: tx.v1.GetHistoryResponse.transactions:type_name -> tx.v1.Transaction
+	13, // 6: tx.v1.Transaction.timestamp:type_name -> google.protobuf.Timestamp
+	12, // 7: tx.v1.Transaction.metadata:type_name -> google.protobuf.Struct
+	0,  // 8: tx.v1.TxService.CreateUser:input_type -> tx.v1.CreateUserRequest
+	2,  // 9: tx.v1.TxService.CreateTx:input_type -> tx.v1.CreateTxRequest
+	2,  // 10: tx.v1.TxService.QuoteTx:input_type -> tx.v1.CreateTxRequest
+	6,  // 11: tx.v1.TxService.GetBalance:input_type -> tx.v1.GetBalanceRequest
+	8,  // 12: tx.v1.TxService.GetHistory:input_type -> tx.v1.GetHistoryRequest
+	1,  // 13: tx.v1.TxService.CreateUser:output_type -> tx.v1.CreateUserResponse
+	3,  // 14: tx.v1.TxService.CreateTx:output_type -> tx.v1.CreateTxResponse
+	4,  // 15: tx.v1.TxService.QuoteTx:output_type -> tx.v1.QuoteTxResponse
+	7,  // 16: tx.v1.TxService.GetBalance:output_type -> tx.v1.GetBalanceResponse
+	9,  // 17: tx.v1.TxService.GetHistory:output_type -> tx.v1.GetHistoryResponse
+	13, // [13:18] is the sub-list for method output_type
+	8,  // [8:13] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_tx_v1_tx_proto_init() }
+func file_tx_v1_tx_proto_init() {
+	if File_tx_v1_tx_proto != nil {
+		return
+	}
+	file_tx_v1_tx_proto_msgTypes[2].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_tx_v1_tx_proto_rawDesc), len(file_tx_v1_tx_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_tx_v1_tx_proto_goTypes,
+		DependencyIndexes: file_tx_v1_tx_proto_depIdxs,
+		MessageInfos:      file_tx_v1_tx_proto_msgTypes,
+	}.Build()
+	File_tx_v1_tx_proto = out.File
+	file_tx_v1_tx_proto_goTypes = nil
+	file_tx_v1_tx_proto_depIdxs = nil
+}
@@ -0,0 +1,295 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: tx/v1/tx.proto
+
+package txv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TxService_CreateUser_FullMethodName = "/tx.v1.TxService/CreateUser"
+	TxService_CreateTx_FullMethodName   = "/tx.v1.TxService/CreateTx"
+	TxService_QuoteTx_FullMethodName    = "/tx.v1.TxService/QuoteTx"
+	TxService_GetBalance_FullMethodName = "/tx.v1.TxService/GetBalance"
+	TxService_GetHistory_FullMethodName = "/tx.v1.TxService/GetHistory"
+)
+
+// TxServiceClient is the client API for TxService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// TxService mirrors the REST API. Errors are mapped to the status codes consistently with the HTTP ones:
+// NOT_FOUND for an unknown user, INVALID_ARGUMENT for a malformed request,
+// FAILED_PRECONDITION for a transfer the balances or the expected state of the sender do not allow,
+// ALREADY_EXISTS for a repeated external reference, PERMISSION_DENIED for a transfer the policy stops,
+// INTERNAL otherwise.
+type TxServiceClient interface {
+	// CreateUser creates a user with the initial balance taken from the primary user.
+	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*CreateUserResponse, error)
+	// CreateTx transfers value between two users.
+	CreateTx(ctx context.Context, in *CreateTxRequest, opts ...grpc.CallOption) (*CreateTxResponse, error)
+	// QuoteTx runs the transfer like CreateTx, but never commits it.
+	QuoteTx(ctx context.Context, in *CreateTxRequest, opts ...grpc.CallOption) (*QuoteTxResponse, error)
+	// GetBalance returns the current balance, or the balance as of at if it is set.
+	GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*GetBalanceResponse, error)
+	// GetHistory returns the latest transactions of the user matching the filters, the newest first.
+	GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error)
+}
+
+type txServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTxServiceClient(cc grpc.ClientConnInterface) TxServiceClient {
+	return &txServiceClient{cc}
+}
+
+func (c *txServiceClient) CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*CreateUserResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateUserResponse)
+	err := c.cc.Invoke(ctx, TxService_CreateUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *txServiceClient) CreateTx(ctx context.Context, in *CreateTxRequest, opts ...grpc.CallOption) (*CreateTxResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateTxResponse)
+	err := c.cc.Invoke(ctx, TxService_CreateTx_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *txServiceClient) QuoteTx(ctx context.Context, in *CreateTxRequest, opts ...grpc.CallOption) (*QuoteTxResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QuoteTxResponse)
+	err := c.cc.Invoke(ctx, TxService_QuoteTx_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *txServiceClient) GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*GetBalanceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetBalanceResponse)
+	err := c.cc.Invoke(ctx, TxService_GetBalance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *txServiceClient) GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetHistoryResponse)
+	err := c.cc.Invoke(ctx, TxService_GetHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TxServiceServer is the server API for TxService service.
+// All implementations must embed UnimplementedTxServiceServer
+// for forward compatibility.
+//
+// TxService mirrors the REST API. Errors are mapped to the status codes consistently with the HTTP ones:
+// NOT_FOUND for an unknown user, INVALID_ARGUMENT for a malformed request,
+// FAILED_PRECONDITION for a transfer the balances or the expected state of the sender do not allow,
+// ALREADY_EXISTS for a repeated external reference, PERMISSION_DENIED for a transfer the policy stops,
+// INTERNAL otherwise.
+type TxServiceServer interface {
+	// CreateUser creates a user with the initial balance taken from the primary user.
+	CreateUser(context.Context, *CreateUserRequest) (*CreateUserResponse, error)
+	// CreateTx transfers value between two users.
+	CreateTx(context.Context, *CreateTxRequest) (*CreateTxResponse, error)
+	// QuoteTx runs the transfer like CreateTx, but never commits it.
+	QuoteTx(context.Context, *CreateTxRequest) (*QuoteTxResponse, error)
+	// GetBalance returns the current balance, or the balance as of at if it is set.
+	GetBalance(context.Context, *GetBalanceRequest) (*GetBalanceResponse, error)
+	// GetHistory returns the latest transactions of the user matching the filters, the newest first.
+	GetHistory(context.Context, *GetHistoryRequest) (*GetHistoryResponse, error)
+	mustEmbedUnimplementedTxServiceServer()
+}
+
+// UnimplementedTxServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTxServiceServer struct{}
+
+func (UnimplementedTxServiceServer) CreateUser(context.Context, *CreateUserRequest) (*CreateUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
+}
+func (UnimplementedTxServiceServer) CreateTx(context.Context, *CreateTxRequest) (*CreateTxResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateTx not implemented")
+}
+func (UnimplementedTxServiceServer) QuoteTx(context.Context, *CreateTxRequest) (*QuoteTxResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QuoteTx not implemented")
+}
+func (UnimplementedTxServiceServer) GetBalance(context.Context, *GetBalanceRequest) (*GetBalanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalance not implemented")
+}
+func (UnimplementedTxServiceServer) GetHistory(context.Context, *GetHistoryRequest) (*GetHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHistory not implemented")
+}
+func (UnimplementedTxServiceServer) mustEmbedUnimplementedTxServiceServer() {}
+func (UnimplementedTxServiceServer) testEmbeddedByValue()                   {}
+
+// UnsafeTxServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TxServiceServer will
+// result in compilation errors.
+type UnsafeTxServiceServer interface {
+	mustEmbedUnimplementedTxServiceServer()
+}
+
+func RegisterTxServiceServer(s grpc.ServiceRegistrar, srv TxServiceServer) {
+	// If the following call pancis, it indicates UnimplementedTxServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TxService_ServiceDesc, srv)
+}
+
+func _TxService_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TxServiceServer).CreateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TxService_CreateUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TxServiceServer).CreateUser(ctx, req.(*CreateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TxService_CreateTx_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTxRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TxServiceServer).CreateTx(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TxService_CreateTx_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TxServiceServer).CreateTx(ctx, req.(*CreateTxRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TxService_QuoteTx_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTxRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TxServiceServer).QuoteTx(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TxService_QuoteTx_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TxServiceServer).QuoteTx(ctx, req.(*CreateTxRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TxService_GetBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TxServiceServer).GetBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TxService_GetBalance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TxServiceServer).GetBalance(ctx, req.(*GetBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TxService_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TxServiceServer).GetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TxService_GetHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TxServiceServer).GetHistory(ctx, req.(*GetHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TxService_ServiceDesc is the grpc.ServiceDesc for TxService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TxService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tx.v1.TxService",
+	HandlerType: (*TxServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateUser",
+			Handler:    _TxService_CreateUser_Handler,
+		},
+		{
+			MethodName: "CreateTx",
+			Handler:    _TxService_CreateTx_Handler,
+		},
+		{
+			MethodName: "QuoteTx",
+			Handler:    _TxService_QuoteTx_Handler,
+		},
+		{
+			MethodName: "GetBalance",
+			Handler:    _TxService_GetBalance_Handler,
+		},
+		{
+			MethodName: "GetHistory",
+			Handler:    _TxService_GetHistory_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "tx/v1/tx.proto",
+}
@@ -45,6 +45,12 @@ func init() {
             "schema": {
               "$ref": "#/definitions/Tx"
             }
+          },
+          {
+            "type": "string",
+            "description": "The ETag of the sender balance. The transfer fails with 412 if the sender has changed since.",
+            "name": "If-Match",
+            "in": "header"
           }
         ],
         "responses": {
@@ -63,6 +69,42 @@ func init() {
         }
       }
     },
+    "/tx/quote": {
+      "post": {
+        "description": "Runs the transaction with its checks, but never commits it. A rule violation is returned in the quote, the balances are not projected then.",
+        "summary": "quote transaction",
+        "operationId": "quoteTx",
+        "parameters": [
+          {
+            "name": "tx",
+            "in": "body",
+            "schema": {
+              "$ref": "#/definitions/Tx"
+            }
+          },
+          {
+            "type": "string",
+            "description": "The ETag of the sender balance. The transfer fails with 412 if the sender has changed since.",
+            "name": "If-Match",
+            "in": "header"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "transaction quote",
+            "schema": {
+              "$ref": "#/definitions/TxQuote"
+            }
+          },
+          "default": {
+            "description": "generic error response",
+            "schema": {
+              "$ref": "#/definitions/error"
+            }
+          }
+        }
+      }
+    },
     "/user": {
       "post": {
         "summary": "create user",
@@ -91,6 +133,48 @@ func init() {
           }
         }
       }
+    },
+    "/user/{id}/balance": {
+      "get": {
+        "description": "Returns the current balance of the user or, if at is set, the balance as of that moment.",
+        "summary": "get user balance",
+        "operationId": "getBalance",
+        "parameters": [
+          {
+            "pattern": "^[0-9a-f]{32}$",
+            "type": "string",
+            "name": "id",
+            "in": "path",
+            "required": true
+          },
+          {
+            "type": "string",
+            "format": "date-time",
+            "name": "at",
+            "in": "query"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "user balance",
+            "schema": {
+              "$ref": "#/definitions/UserBalance"
+            },
+            "headers": {
+              "ETag": {
+                "type": "string",
+                "description": "The version of the current balance, for If-Match of a transfer. Not set for the primary account and past balances."
+              }
+            }
+          },
+          "default": {
+            "description": "generic error response",
+            "schema": {
+              "$ref": "#/definitions/error"
+            }
+          }
+        }
+      }
     }
   },
   "definitions": {
@@ -119,7 +203,8 @@ func init() {
       "properties": {
         "balance": {
           "type": "integer",
-          "format": "int64"
+          "format": "int64",
+          "minimum": 0
         }
       }
     },
@@ -142,17 +227,112 @@ func init() {
         "value"
       ],
       "properties": {
+        "description": {
+          "type": "string",
+          "maxLength": 1024
+        },
+        "expected_balance": {
+          "description": "The transfer fails with 412 if the sender balance is not this one.",
+          "type": "integer",
+          "format": "int64",
+          "x-nullable": true
+        },
+        "external_reference": {
+          "description": "Unique among the transactions of the sender, so a repeated request is rejected with 409.",
+          "type": "string",
+          "maxLength": 128
+        },
         "from": {
           "type": "string",
           "pattern": "^[0-9a-f]{32}$"
         },
+        "metadata": {
+          "description": "Free-form data stored with the transaction, at most 4096 bytes as JSON.",
+          "type": "object",
+          "additionalProperties": {}
+        },
         "to": {
           "type": "string",
           "pattern": "^[0-9a-f]{32}$"
         },
         "value": {
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        }
+      }
+    },
+    "TxQuote": {
+      "type": "object",
+      "required": [
+        "fee"
+      ],
+      "properties": {
+        "fee": {
+          "description": "Always 0, the ledger charges no fees yet.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "new_balance_from": {
+          "type": "integer",
+          "format": "int64",
+          "x-nullable": true
+        },
+        "new_balance_to": {
+          "type": "integer",
+          "format": "int64",
+          "x-nullable": true
+        },
+        "violations": {
+          "description": "The deny and review decisions of all the rules, in their order, if the transfer would be stopped.",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/Violation"
+          }
+        }
+      }
+    },
+    "UserBalance": {
+      "type": "object",
+      "required": [
+        "id",
+        "balance"
+      ],
+      "properties": {
+        "at": {
+          "type": "string",
+          "format": "date-time"
+        },
+        "balance": {
           "type": "integer",
           "format": "int64"
+        },
+        "id": {
+          "type": "string"
+        }
+      }
+    },
+    "Violation": {
+      "type": "object",
+      "required": [
+        "decision",
+        "rule",
+        "reason",
+        "message"
+      ],
+      "properties": {
+        "decision": {
+          "description": "deny or review",
+          "type": "string"
+        },
+        "message": {
+          "type": "string"
+        },
+        "reason": {
+          "type": "string"
+        },
+        "rule": {
+          "type": "string"
         }
       }
     },
@@ -201,6 +381,12 @@ func init() {
             "schema": {
               "$ref": "#/definitions/Tx"
             }
+          },
+          {
+            "type": "string",
+            "description": "The ETag of the sender balance. The transfer fails with 412 if the sender has changed since.",
+            "name": "If-Match",
+            "in": "header"
           }
         ],
         "responses": {
@@ -219,6 +405,42 @@ func init() {
         }
       }
     },
+    "/tx/quote": {
+      "post": {
+        "description": "Runs the transaction with its checks, but never commits it. A rule violation is returned in the quote, the balances are not projected then.",
+        "summary": "quote transaction",
+        "operationId": "quoteTx",
+        "parameters": [
+          {
+            "name": "tx",
+            "in": "body",
+            "schema": {
+              "$ref": "#/definitions/Tx"
+            }
+          },
+          {
+            "type": "string",
+            "description": "The ETag of the sender balance. The transfer fails with 412 if the sender has changed since.",
+            "name": "If-Match",
+            "in": "header"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "transaction quote",
+            "schema": {
+              "$ref": "#/definitions/TxQuote"
+            }
+          },
+          "default": {
+            "description": "generic error response",
+            "schema": {
+              "$ref": "#/definitions/error"
+            }
+          }
+        }
+      }
+    },
     "/user": {
       "post": {
         "summary": "create user",
@@ -247,6 +469,48 @@ func init() {
           }
         }
       }
+    },
+    "/user/{id}/balance": {
+      "get": {
+        "description": "Returns the current balance of the user or, if at is set, the balance as of that moment.",
+        "summary": "get user balance",
+        "operationId": "getBalance",
+        "parameters": [
+          {
+            "pattern": "^[0-9a-f]{32}$",
+            "type": "string",
+            "name": "id",
+            "in": "path",
+            "required": true
+          },
+          {
+            "type": "string",
+            "format": "date-time",
+            "name": "at",
+            "in": "query"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "user balance",
+            "schema": {
+              "$ref": "#/definitions/UserBalance"
+            },
+            "headers": {
+              "ETag": {
+                "type": "string",
+                "description": "The version of the current balance, for If-Match of a transfer. Not set for the primary account and past balances."
+              }
+            }
+          },
+          "default": {
+            "description": "generic error response",
+            "schema": {
+              "$ref": "#/definitions/error"
+            }
+          }
+        }
+      }
     }
   },
   "definitions": {
@@ -299,10 +563,30 @@ func init() {
         "value"
       ],
       "properties": {
+        "description": {
+          "type": "string",
+          "maxLength": 1024
+        },
+        "expected_balance": {
+          "description": "The transfer fails with 412 if the sender balance is not this one.",
+          "type": "integer",
+          "format": "int64",
+          "x-nullable": true
+        },
+        "external_reference": {
+          "description": "Unique among the transactions of the sender, so a repeated request is rejected with 409.",
+          "type": "string",
+          "maxLength": 128
+        },
         "from": {
           "type": "string",
           "pattern": "^[0-9a-f]{32}$"
         },
+        "metadata": {
+          "description": "Free-form data stored with the transaction, at most 4096 bytes as JSON.",
+          "type": "object",
+          "additionalProperties": {}
+        },
         "to": {
           "type": "string",
           "pattern": "^[0-9a-f]{32}$"
@@ -314,6 +598,80 @@ func init() {
         }
       }
     },
+    "TxQuote": {
+      "type": "object",
+      "required": [
+        "fee"
+      ],
+      "properties": {
+        "fee": {
+          "description": "Always 0, the ledger charges no fees yet.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "new_balance_from": {
+          "type": "integer",
+          "format": "int64",
+          "x-nullable": true
+        },
+        "new_balance_to": {
+          "type": "integer",
+          "format": "int64",
+          "x-nullable": true
+        },
+        "violations": {
+          "description": "The deny and review decisions of all the rules, in their order, if the transfer would be stopped.",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/Violation"
+          }
+        }
+      }
+    },
+    "UserBalance": {
+      "type": "object",
+      "required": [
+        "id",
+        "balance"
+      ],
+      "properties": {
+        "at": {
+          "type": "string",
+          "format": "date-time"
+        },
+        "balance": {
+          "type": "integer",
+          "format": "int64"
+        },
+        "id": {
+          "type": "string"
+        }
+      }
+    },
+    "Violation": {
+      "type": "object",
+      "required": [
+        "decision",
+        "rule",
+        "reason",
+        "message"
+      ],
+      "properties": {
+        "decision": {
+          "description": "deny or review",
+          "type": "string"
+        },
+        "message": {
+          "type": "string"
+        },
+        "reason": {
+          "type": "string"
+        },
+        "rule": {
+          "type": "string"
+        }
+      }
+    },
     "error": {
       "type": "object",
       "required": [
@@ -48,6 +48,12 @@ func NewTxAPI(spec *loads.Document) *TxAPI {
 		CreateUserHandler: CreateUserHandlerFunc(func(params CreateUserParams) middleware.Responder {
 			return middleware.NotImplemented("operation CreateUser has not yet been implemented")
 		}),
+		GetBalanceHandler: GetBalanceHandlerFunc(func(params GetBalanceParams) middleware.Responder {
+			return middleware.NotImplemented("operation GetBalance has not yet been implemented")
+		}),
+		QuoteTxHandler: QuoteTxHandlerFunc(func(params QuoteTxParams) middleware.Responder {
+			return middleware.NotImplemented("operation QuoteTx has not yet been implemented")
+		}),
 	}
 }
 
@@ -88,6 +94,10 @@ type TxAPI struct {
 	CreateTxHandler CreateTxHandler
 	// CreateUserHandler sets the operation handler for the create user operation
 	CreateUserHandler CreateUserHandler
+	// GetBalanceHandler sets the operation handler for the get balance operation
+	GetBalanceHandler GetBalanceHandler
+	// QuoteTxHandler sets the operation handler for the quote tx operation
+	QuoteTxHandler QuoteTxHandler
 
 	// ServeError is called when an error is received, there is a default handler
 	// but you can set your own with this
@@ -171,6 +181,12 @@ func (o *TxAPI) Validate() error {
 	if o.CreateUserHandler == nil {
 		unregistered = append(unregistered, "CreateUserHandler")
 	}
+	if o.GetBalanceHandler == nil {
+		unregistered = append(unregistered, "GetBalanceHandler")
+	}
+	if o.QuoteTxHandler == nil {
+		unregistered = append(unregistered, "QuoteTxHandler")
+	}
 
 	if len(unregistered) > 0 {
 		return fmt.Errorf("missing registration: %s", strings.Join(unregistered, ", "))
@@ -267,6 +283,14 @@ func (o *TxAPI) initHandlerCache() {
 		o.handlers["POST"] = make(map[string]http.Handler)
 	}
 	o.handlers["POST"]["/user"] = NewCreateUser(o.context, o.CreateUserHandler)
+	if o.handlers["GET"] == nil {
+		o.handlers["GET"] = make(map[string]http.Handler)
+	}
+	o.handlers["GET"]["/user/{id}/balance"] = NewGetBalance(o.context, o.GetBalanceHandler)
+	if o.handlers["POST"] == nil {
+		o.handlers["POST"] = make(map[string]http.Handler)
+	}
+	o.handlers["POST"]["/tx/quote"] = NewQuoteTx(o.context, o.QuoteTxHandler)
 }
 
 // Serve creates a http handler to serve the API over HTTP